@@ -0,0 +1,255 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Segren/testTask/internal/data"
+	"github.com/Segren/testTask/internal/validator"
+)
+
+// @Summary Get API keys
+// @Description List API keys of the current user. Secrets are never returned after creation.
+// @Tags api-keys
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} data.APIKey "API keys"
+// @Failure 401 {string} string "Authentication required"
+// @Failure 403 {string} string "Account not activated or not permitted"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api-keys [get]
+func (app *application) listAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	keys, err := app.models.APIKeys.GetAllForUser(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"api_keys": keys}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// @Summary Create an API key
+// @Description Create a long-lived key for the X-API-Key header. Scopes must be a subset of the user's permissions. The key is returned only once.
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param api_key body object true "Name, scopes and optional expires_at"
+// @Success 201 {object} data.APIKey "The newly created key including its secret"
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Authentication required"
+// @Failure 403 {string} string "Account not activated or not permitted"
+// @Failure 422 {string} string "Validation error"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api-keys [post]
+func (app *application) createAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name      string     `json:"name"`
+		Scopes    []string   `json:"scopes"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	permissions, err := app.models.Permissions.GetAllForUser(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	key := &data.APIKey{
+		UserID:    user.ID,
+		Name:      input.Name,
+		Scopes:    input.Scopes,
+		ExpiresAt: input.ExpiresAt,
+	}
+
+	v := validator.New()
+
+	if data.ValidateAPIKey(v, key, permissions); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.APIKeys.Insert(key)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/api-keys/%d", key.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"api_key": key}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// @Summary Get an API key
+// @Tags api-keys
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "API key ID"
+// @Success 200 {object} data.APIKey "API key"
+// @Failure 401 {string} string "Authentication required"
+// @Failure 403 {string} string "Account not activated or not permitted"
+// @Failure 404 {string} string "API key not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api-keys/{id} [get]
+func (app *application) showAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	key, ok := app.readAPIKey(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.writeJSON(w, http.StatusOK, envelope{"api_key": key}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// @Summary Rotate an API key
+// @Description Issue a replacement key with the same name and scopes. The old key keeps working for grace_period (Go duration, default 24h, at most 168h).
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "API key ID"
+// @Param rotation body object false "Optional grace_period"
+// @Success 201 {object} data.APIKey "The replacement key including its secret"
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Authentication required"
+// @Failure 403 {string} string "Account not activated or not permitted"
+// @Failure 404 {string} string "API key not found"
+// @Failure 409 {string} string "Edit conflict occurred"
+// @Failure 422 {string} string "Validation error"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api-keys/{id}/rotate [post]
+func (app *application) rotateAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	old, ok := app.readAPIKey(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		GracePeriod string `json:"grace_period"`
+	}
+
+	//тело необязательно: без него действует срок по умолчанию
+	err := app.readJSON(w, r, &input)
+	if err != nil && !errors.Is(err, errEmptyBody) {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	gracePeriod := 24 * time.Hour
+	if input.GracePeriod != "" {
+		gracePeriod, err = time.ParseDuration(input.GracePeriod)
+		v.Check(err == nil, "grace_period", "must be a valid duration such as 24h")
+	}
+
+	v.Check(gracePeriod >= 0, "grace_period", "must not be negative")
+	v.Check(gracePeriod <= data.MaxAPIKeyGracePeriod, "grace_period", "must not be more than 168h")
+	v.Check(old.Active() && old.ReplacedBy == nil, "api_key", "must be active and not already rotated")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	key, err := app.models.APIKeys.Rotate(old, gracePeriod)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/api-keys/%d", key.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"api_key": key}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// @Summary Revoke an API key
+// @Tags api-keys
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "API key ID"
+// @Success 200 {string} string "Message indicating successful revocation"
+// @Failure 401 {string} string "Authentication required"
+// @Failure 403 {string} string "Account not activated or not permitted"
+// @Failure 404 {string} string "API key not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api-keys/{id} [delete]
+func (app *application) revokeAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	err = app.models.APIKeys.Revoke(user.ID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "api key successfully revoked"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// api-ключ текущего пользователя из параметров маршрута. При ошибке ответ уже отправлен
+func (app *application) readAPIKey(w http.ResponseWriter, r *http.Request) (*data.APIKey, bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	user := app.contextGetUser(r)
+
+	key, err := app.models.APIKeys.GetForUser(user.ID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	return key, true
+}
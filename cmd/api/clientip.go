@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// значение флага -trusted-proxies: список подсетей вида "10.0.0.0/8,127.0.0.1"
+type trustedProxies []netip.Prefix
+
+func (t *trustedProxies) String() string {
+	var parts []string
+	for _, prefix := range *t {
+		parts = append(parts, prefix.String())
+	}
+
+	return strings.Join(parts, ",")
+}
+
+func (t *trustedProxies) Set(value string) error {
+	*t = nil
+
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		//отдельный адрес считается подсетью из одного адреса
+		if !strings.Contains(part, "/") {
+			addr, err := netip.ParseAddr(part)
+			if err != nil {
+				return fmt.Errorf("invalid trusted proxy %q", part)
+			}
+
+			*t = append(*t, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(part)
+		if err != nil {
+			return fmt.Errorf("invalid trusted proxy %q", part)
+		}
+
+		*t = append(*t, prefix.Masked())
+	}
+
+	return nil
+}
+
+func (t trustedProxies) contains(addr netip.Addr) bool {
+	addr = addr.Unmap()
+
+	for _, prefix := range t {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// адрес клиента. Заголовки Forwarded, X-Forwarded-For и X-Real-IP учитываются,
+// только если запрос пришел от доверенного прокси. Цепочка адресов просматривается
+// справа налево до первого недоверенного адреса
+func (t trustedProxies) clientIP(r *http.Request) string {
+	remote, ok := parseNode(r.RemoteAddr)
+	if !ok {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			return r.RemoteAddr
+		}
+		return host
+	}
+
+	if !t.contains(remote) {
+		return remote.String()
+	}
+
+	hops := forwardedFor(r.Header)
+	if hops == nil {
+		hops = splitList(strings.Join(r.Header.Values("X-Forwarded-For"), ","))
+	}
+	if hops == nil {
+		hops = splitList(r.Header.Get("X-Real-IP"))
+	}
+
+	client := remote
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, ok := parseNode(hops[i])
+		//скрытый (unknown, _hidden) или некорректный адрес: дальше по цепочке верить нельзя
+		if !ok {
+			break
+		}
+
+		client = addr
+
+		if !t.contains(addr) {
+			break
+		}
+	}
+
+	return client.String()
+}
+
+// адреса из параметров for заголовка Forwarded (RFC 7239) или nil, если заголовка нет
+func forwardedFor(header http.Header) []string {
+	values := header.Values("Forwarded")
+	if len(values) == 0 {
+		return nil
+	}
+
+	hops := []string{}
+
+	for _, element := range splitQuoted(strings.Join(values, ","), ',') {
+		node := ""
+
+		for _, pair := range splitQuoted(element, ';') {
+			key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if ok && strings.EqualFold(key, "for") {
+				node = strings.Trim(value, `"`)
+			}
+		}
+
+		//элемент без for тоже является звеном цепочки
+		hops = append(hops, node)
+	}
+
+	return hops
+}
+
+// адрес узла в одной из форм: 192.0.2.1, 192.0.2.1:4711, [2001:db8::1], [2001:db8::1]:4711, 2001:db8::1
+func parseNode(s string) (netip.Addr, bool) {
+	s = strings.TrimSpace(s)
+
+	if addrPort, err := netip.ParseAddrPort(s); err == nil {
+		return addrPort.Addr().Unmap(), true
+	}
+
+	addr, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(s, "["), "]"))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+
+	return addr.Unmap(), true
+}
+
+// список через запятую или nil для пустой строки
+func splitList(s string) []string {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+
+	return strings.Split(s, ",")
+}
+
+// разбивает строку по sep вне кавычек
+func splitQuoted(s string, sep rune) []string {
+	var parts []string
+
+	quoted := false
+	start := 0
+
+	for i, c := range s {
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case c == sep && !quoted:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(parts, s[start:])
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTrustedProxiesClientIP(t *testing.T) {
+	var proxies trustedProxies
+	err := proxies.Set("10.0.0.0/8, 127.0.0.1, 2001:db8:ffff::/48")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		remote  string
+		headers map[string]string
+		want    string
+	}{
+		{
+			name:   "no headers",
+			remote: "203.0.113.9:5000",
+			want:   "203.0.113.9",
+		},
+		{
+			name:    "spoofed header from untrusted client",
+			remote:  "203.0.113.9:5000",
+			headers: map[string]string{"X-Forwarded-For": "1.2.3.4", "X-Real-IP": "1.2.3.4", "Forwarded": "for=1.2.3.4"},
+			want:    "203.0.113.9",
+		},
+		{
+			name:    "client behind trusted proxy",
+			remote:  "10.0.0.1:5000",
+			headers: map[string]string{"X-Forwarded-For": "198.51.100.7"},
+			want:    "198.51.100.7",
+		},
+		{
+			//клиент сам дописал адрес в начало цепочки, прокси добавил настоящий
+			name:    "spoofed first hop",
+			remote:  "10.0.0.1:5000",
+			headers: map[string]string{"X-Forwarded-For": "6.6.6.6, 198.51.100.7"},
+			want:    "198.51.100.7",
+		},
+		{
+			name:    "chain of trusted proxies",
+			remote:  "10.0.0.1:5000",
+			headers: map[string]string{"X-Forwarded-For": "198.51.100.7, 10.0.0.3, 10.0.0.2"},
+			want:    "198.51.100.7",
+		},
+		{
+			name:    "only trusted hops",
+			remote:  "10.0.0.1:5000",
+			headers: map[string]string{"X-Forwarded-For": "10.0.0.3"},
+			want:    "10.0.0.3",
+		},
+		{
+			name:    "invalid hop stops the chain",
+			remote:  "10.0.0.1:5000",
+			headers: map[string]string{"X-Forwarded-For": "198.51.100.7, not-an-ip"},
+			want:    "10.0.0.1",
+		},
+		{
+			name:    "x-real-ip",
+			remote:  "127.0.0.1:5000",
+			headers: map[string]string{"X-Real-IP": "198.51.100.7"},
+			want:    "198.51.100.7",
+		},
+		{
+			name:    "forwarded quoted ipv6 with port",
+			remote:  "10.0.0.1:5000",
+			headers: map[string]string{"Forwarded": `for="[2001:db8::17]:4711"`},
+			want:    "2001:db8::17",
+		},
+		{
+			name:    "forwarded through trusted ipv6 proxy",
+			remote:  "[2001:db8:ffff::2]:443",
+			headers: map[string]string{"Forwarded": `for=192.0.2.60;proto=http, for="[2001:db8:ffff::1]"`},
+			want:    "192.0.2.60",
+		},
+		{
+			name:    "forwarded quoted separator",
+			remote:  "10.0.0.1:5000",
+			headers: map[string]string{"Forwarded": `for=198.51.100.7;by="a,b;c"`},
+			want:    "198.51.100.7",
+		},
+		{
+			name:    "forwarded takes precedence",
+			remote:  "10.0.0.1:5000",
+			headers: map[string]string{"Forwarded": "For=198.51.100.7", "X-Forwarded-For": "6.6.6.6"},
+			want:    "198.51.100.7",
+		},
+		{
+			name:    "forwarded hidden node",
+			remote:  "10.0.0.1:5000",
+			headers: map[string]string{"Forwarded": "for=unknown, for=10.0.0.5"},
+			want:    "10.0.0.5",
+		},
+		{
+			name:    "ipv4-mapped proxy address",
+			remote:  "[::ffff:10.0.0.1]:5000",
+			headers: map[string]string{"X-Forwarded-For": "198.51.100.7"},
+			want:    "198.51.100.7",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remote
+			for key, value := range tt.headers {
+				r.Header.Set(key, value)
+			}
+
+			if got := proxies.clientIP(r); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrustedProxiesSet(t *testing.T) {
+	var proxies trustedProxies
+
+	err := proxies.Set("10.1.2.3/8, ::1, ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := proxies.String(); got != "10.0.0.0/8,::1/128" {
+		t.Errorf("got %q", got)
+	}
+
+	for _, value := range []string{"10.0.0.0/33", "proxy.local", "10.0.0"} {
+		if err := proxies.Set(value); err == nil {
+			t.Errorf("Set(%q) accepted an invalid proxy", value)
+		}
+	}
+}
@@ -0,0 +1,447 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/Segren/testTask/internal/configfile"
+	"github.com/Segren/testTask/internal/jsonlog"
+	"github.com/Segren/testTask/internal/validator"
+	"gopkg.in/yaml.v2"
+)
+
+type config struct {
+	port int
+	env  string
+	db   struct {
+		dsn          string
+		maxOpenConns int
+		maxIdleConns int
+		maxIdleTime  string
+	}
+	//для конфигурации кол-ва запросов в секунду и во время бурста
+	limiter struct {
+		rps     float64
+		burst   int
+		enabled bool
+		//лимиты пользователей, api-ключей и входа; анонимные клиенты ограничиваются rps и burst
+		tiers limiterTiers
+		//доля лимита чтения для изменяющих запросов
+		writeRatio float64
+		//хранилище лимитов: memory или postgres
+		store string
+	}
+	//внешнее api с информацией о песнях и кеш его ответов
+	provider struct {
+		url     string
+		timeout time.Duration
+		cache   struct {
+			size        int
+			ttl         time.Duration
+			negativeTTL time.Duration
+			persistent  bool
+		}
+	}
+	//фоновая проверка внешних ссылок песен
+	linkChecker struct {
+		enabled     bool
+		interval    time.Duration
+		workers     int
+		timeout     time.Duration
+		maxFailures int
+	}
+	//фоновый пересчет похожих песен для рекомендаций
+	similarity struct {
+		enabled  bool
+		interval time.Duration
+	}
+	//отправка писем: log, file или smtp
+	mailer struct {
+		kind   string
+		dir    string
+		sender string
+		smtp   struct {
+			host     string
+			port     int
+			username string
+			password string
+		}
+	}
+	//источники, которым разрешены запросы из браузера
+	cors struct {
+		trustedOrigins   originList
+		allowCredentials bool
+	}
+	//подсети прокси, которым разрешено передавать адрес клиента в заголовках
+	trustedProxies trustedProxies
+	//сколько сервер сообщает о неготовности перед остановкой, чтобы балансировщик увел трафик
+	shutdownDelay time.Duration
+	//запись каждого запроса в журнал
+	accessLog bool
+	log       struct {
+		level       jsonlog.Level
+		stackTraces bool
+	}
+	//выгрузка спанов трассировки: none или stdout
+	traceExporter string
+	//путь метрик в формате Prometheus, пустая строка отключает их
+	metricsPath string
+	//отдельный адрес для метрик и /debug/vars. Пустая строка - порт api, только для администраторов
+	metricsAddr string
+	//чтение песен без аутентификации
+	songsPublicRead bool
+	//применять миграции при запуске
+	autoMigrate bool
+
+	//файл настроек в формате yaml
+	configFile     string
+	printConfig    bool
+	displayVersion bool
+
+	//флаги, через которые заполнен config, для -print-config
+	flags *flag.FlagSet
+	//аргументы командной строки для повторной загрузки по SIGHUP
+	args []string
+}
+
+// флаги, которые задаются только в командной строке
+var commandLineOnlyFlags = []string{"config", "print-config", "version"}
+
+// флаги с секретами, которые -print-config не показывает
+var secretFlags = []string{"db-dsn", "smtp-password"}
+
+// ключи yaml, которые не получаются из имени флага
+var configFileAliases = map[string]string{
+	"mailer-kind": "mailer",
+}
+
+// набор флагов всех настроек. Значения по умолчанию сразу записываются в cfg
+func (cfg *config) flagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("api", flag.ContinueOnError)
+
+	fs.StringVar(&cfg.configFile, "config", "", "Path to a YAML configuration file")
+	fs.BoolVar(&cfg.printConfig, "print-config", false, "Print the effective configuration with secrets redacted and exit")
+
+	fs.IntVar(&cfg.port, "port", 8080, "API server port")
+	fs.DurationVar(&cfg.shutdownDelay, "shutdown-delay", 0, "How long /readyz reports not ready before the server stops accepting connections")
+	fs.StringVar(&cfg.env, "env", "development", "Environment (development|staging|production)")
+
+	fs.StringVar(&cfg.db.dsn, "db-dsn", "", "PostgreSQL DSN")
+
+	fs.IntVar(&cfg.db.maxOpenConns, "db-max-open-conns", 25, "PostgreSQL max open connections")
+	fs.IntVar(&cfg.db.maxIdleConns, "db-max-idle-conns", 25, "PostgreSQL max idle connections")
+	fs.StringVar(&cfg.db.maxIdleTime, "db-max-idle-time", "15m", "PostgreSQL max connection idle time")
+
+	fs.Float64Var(&cfg.limiter.rps, "limiter-rps", 20, "Rate limiter maximum requests per second")
+	fs.IntVar(&cfg.limiter.burst, "limiter-burst", 50, "Rate limiter maximum burst")
+	fs.BoolVar(&cfg.limiter.enabled, "limiter-enabled", true, "Enable rate limiter")
+	cfg.limiter.tiers = limiterTiers{
+		tierUser:   {Rate: 40, Burst: 100},
+		tierAPIKey: {Rate: 100, Burst: 200},
+		tierAuth:   {Rate: 0.2, Burst: 5},
+	}
+	fs.Var(cfg.limiter.tiers, "limiter-tiers", "Rate limits of authenticated clients as tier=rps:burst pairs (tiers: user, api_key, auth)")
+	fs.Float64Var(&cfg.limiter.writeRatio, "limiter-write-ratio", 0.25, "Share of the read rate limit available for write requests")
+	fs.StringVar(&cfg.limiter.store, "limiter-store", "memory", "Rate limiter state store (memory|postgres); postgres shares limits between instances")
+
+	fs.StringVar(&cfg.provider.url, "provider-url", "http://localhost:8081/info", "Song info provider URL")
+	fs.DurationVar(&cfg.provider.timeout, "provider-timeout", 5*time.Second, "Song info provider request timeout")
+	fs.IntVar(&cfg.provider.cache.size, "provider-cache-size", 1000, "Maximum number of cached provider responses (0 disables in-memory cache)")
+	fs.DurationVar(&cfg.provider.cache.ttl, "provider-cache-ttl", 24*time.Hour, "Provider response cache TTL")
+	fs.DurationVar(&cfg.provider.cache.negativeTTL, "provider-cache-negative-ttl", 10*time.Minute, "Provider not found response cache TTL")
+	fs.BoolVar(&cfg.provider.cache.persistent, "provider-cache-persistent", false, "Store provider responses in PostgreSQL")
+
+	fs.BoolVar(&cfg.linkChecker.enabled, "linkcheck-enabled", true, "Enable background song link checker")
+	fs.DurationVar(&cfg.linkChecker.interval, "linkcheck-interval", 24*time.Hour, "How often each song link is rechecked")
+	fs.IntVar(&cfg.linkChecker.workers, "linkcheck-workers", 5, "Number of concurrent link checks")
+	fs.DurationVar(&cfg.linkChecker.timeout, "linkcheck-timeout", 10*time.Second, "Link check request timeout")
+	fs.IntVar(&cfg.linkChecker.maxFailures, "linkcheck-max-failures", 3, "Consecutive failed checks before a link is marked dead")
+
+	fs.BoolVar(&cfg.similarity.enabled, "similarity-enabled", true, "Enable background song similarity refresh")
+	fs.DurationVar(&cfg.similarity.interval, "similarity-interval", time.Hour, "How often song similarity is recomputed")
+
+	fs.StringVar(&cfg.mailer.kind, "mailer", "log", "Mailer (log|file|smtp)")
+	fs.StringVar(&cfg.mailer.dir, "mailer-dir", "./tmp/mail", "Directory for emails written by the file mailer")
+	fs.StringVar(&cfg.mailer.sender, "mailer-sender", "Music Info <no-reply@musicinfo.local>", "Email sender")
+	fs.StringVar(&cfg.mailer.smtp.host, "smtp-host", "localhost", "SMTP host")
+	fs.IntVar(&cfg.mailer.smtp.port, "smtp-port", 25, "SMTP port")
+	fs.StringVar(&cfg.mailer.smtp.username, "smtp-username", "", "SMTP username")
+	fs.StringVar(&cfg.mailer.smtp.password, "smtp-password", "", "SMTP password")
+
+	fs.Var(&cfg.cors.trustedOrigins, "cors-trusted-origins", "Trusted CORS origins (space or comma separated, * allows any origin)")
+	fs.BoolVar(&cfg.cors.allowCredentials, "cors-allow-credentials", false, "Allow CORS requests with credentials from trusted origins")
+
+	fs.Var(&cfg.trustedProxies, "trusted-proxies", "Comma-separated CIDRs of proxies allowed to set Forwarded, X-Forwarded-For and X-Real-IP")
+
+	fs.BoolVar(&cfg.accessLog, "access-log", true, "Log every request")
+	cfg.log.level = jsonlog.LevelInfo
+	fs.Var(&cfg.log.level, "log-level", "Minimum log level (debug|info|warn|error|fatal|off)")
+	fs.BoolVar(&cfg.log.stackTraces, "log-stack-traces", false, "Include stack traces in error log entries")
+	fs.StringVar(&cfg.traceExporter, "trace-exporter", "none", "Trace span exporter (none|stdout)")
+	fs.StringVar(&cfg.metricsPath, "metrics-path", "/metrics", "Path of the Prometheus metrics endpoint (empty disables it)")
+	fs.StringVar(&cfg.metricsAddr, "metrics-addr", "", "Separate listen address for metrics and /debug/vars without authentication, e.g. 127.0.0.1:9090 (empty serves them on the API port to songs:admin only)")
+
+	fs.BoolVar(&cfg.autoMigrate, "auto-migrate", false, "Apply pending database migrations on startup (guarded by an advisory lock)")
+
+	fs.BoolVar(&cfg.songsPublicRead, "songs-public-read", true, "Allow reading songs without the songs:read permission")
+
+	// булево для отображения версии проекта и выхода
+	fs.BoolVar(&cfg.displayVersion, "version", false, "Display version information and exit")
+
+	return fs
+}
+
+// собирает настройки из значений по умолчанию, файла -config (или MUSIC_CONFIG),
+// переменных окружения MUSIC_* и флагов. Каждый следующий источник важнее предыдущего
+func loadConfig(args []string, getenv func(string) string) (*config, error) {
+	cfg := &config{args: args}
+	cfg.flags = cfg.flagSet()
+
+	err := cfg.flags.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+
+	//флаги из командной строки не перекрываются другими источниками
+	explicit := make(map[string]bool)
+	cfg.flags.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	path := cfg.configFile
+	if path == "" {
+		path = getenv(configfile.EnvName("config"))
+	}
+
+	if path != "" {
+		err = cfg.applyFile(path, explicit)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = cfg.applyEnv(getenv, explicit)
+	if err != nil {
+		return nil, err
+	}
+
+	err = cfg.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// применяет файл настроек, правила ключей описаны в internal/configfile
+func (cfg *config) applyFile(path string, explicit map[string]bool) error {
+	values, err := configfile.Load(path)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		name := key
+		if alias, ok := configFileAliases[key]; ok {
+			name = alias
+		}
+
+		if cfg.flags.Lookup(name) == nil || slices.Contains(commandLineOnlyFlags, name) {
+			return fmt.Errorf("config file %s: unknown option %q", path, key)
+		}
+
+		if explicit[name] {
+			continue
+		}
+
+		err = cfg.flags.Set(name, values[key])
+		if err != nil {
+			return fmt.Errorf("config file %s: %s: %w", path, key, err)
+		}
+	}
+
+	return nil
+}
+
+// применяет переменные окружения MUSIC_*. Пустая переменная считается незаданной
+func (cfg *config) applyEnv(getenv func(string) string, explicit map[string]bool) error {
+	var err error
+
+	cfg.flags.VisitAll(func(f *flag.Flag) {
+		if err != nil || explicit[f.Name] || slices.Contains(commandLineOnlyFlags, f.Name) {
+			return
+		}
+
+		value := getenv(configfile.EnvName(f.Name))
+		if value == "" {
+			return
+		}
+
+		if setErr := cfg.flags.Set(f.Name, value); setErr != nil {
+			err = fmt.Errorf("%s: %w", configfile.EnvName(f.Name), setErr)
+		}
+	})
+
+	return err
+}
+
+// проверяет итоговые настройки. Ключи ошибок - имена флагов
+func (cfg *config) validate() error {
+	v := validator.New()
+
+	v.Check(cfg.port > 0 && cfg.port <= 65535, "port", "must be between 1 and 65535")
+	v.Check(validator.PermittedValue(cfg.env, "development", "staging", "production"), "env", "must be development, staging or production")
+	v.Check(cfg.shutdownDelay >= 0, "shutdown-delay", "must not be negative")
+
+	v.Check(cfg.db.dsn != "", "db-dsn", "must be provided (flag -db-dsn, MUSIC_DB_DSN or db.dsn in the config file)")
+	v.Check(cfg.db.maxOpenConns > 0, "db-max-open-conns", "must be greater than zero")
+	v.Check(cfg.db.maxIdleConns >= 0, "db-max-idle-conns", "must not be negative")
+	_, err := time.ParseDuration(cfg.db.maxIdleTime)
+	v.Check(err == nil, "db-max-idle-time", "must be a valid duration such as 15m")
+
+	v.Check(cfg.limiter.rps > 0, "limiter-rps", "must be greater than zero")
+	v.Check(cfg.limiter.burst > 0, "limiter-burst", "must be greater than zero")
+	v.Check(cfg.limiter.writeRatio > 0 && cfg.limiter.writeRatio <= 1, "limiter-write-ratio", "must be greater than 0 and at most 1")
+	v.Check(validator.PermittedValue(cfg.limiter.store, "memory", "postgres"), "limiter-store", "must be memory or postgres")
+
+	v.Check(validator.IsURL(cfg.provider.url), "provider-url", "must be an absolute http(s) URL")
+	v.Check(cfg.provider.timeout > 0, "provider-timeout", "must be greater than zero")
+	v.Check(cfg.provider.cache.size >= 0, "provider-cache-size", "must not be negative")
+	v.Check(cfg.provider.cache.ttl > 0, "provider-cache-ttl", "must be greater than zero")
+	v.Check(cfg.provider.cache.negativeTTL > 0, "provider-cache-negative-ttl", "must be greater than zero")
+
+	if cfg.linkChecker.enabled {
+		v.Check(cfg.linkChecker.interval > 0, "linkcheck-interval", "must be greater than zero")
+		v.Check(cfg.linkChecker.workers > 0, "linkcheck-workers", "must be greater than zero")
+		v.Check(cfg.linkChecker.timeout > 0, "linkcheck-timeout", "must be greater than zero")
+		v.Check(cfg.linkChecker.maxFailures > 0, "linkcheck-max-failures", "must be greater than zero")
+	}
+
+	if cfg.similarity.enabled {
+		v.Check(cfg.similarity.interval > 0, "similarity-interval", "must be greater than zero")
+	}
+
+	v.Check(validator.PermittedValue(cfg.mailer.kind, "log", "file", "smtp"), "mailer", "must be log, file or smtp")
+	v.Check(cfg.mailer.sender != "", "mailer-sender", "must be provided")
+	if cfg.mailer.kind == "file" {
+		v.Check(cfg.mailer.dir != "", "mailer-dir", "must be provided for the file mailer")
+	}
+	if cfg.mailer.kind == "smtp" {
+		v.Check(cfg.mailer.smtp.host != "", "smtp-host", "must be provided for the smtp mailer")
+		v.Check(cfg.mailer.smtp.port > 0 && cfg.mailer.smtp.port <= 65535, "smtp-port", "must be between 1 and 65535")
+	}
+
+	for _, origin := range cfg.cors.trustedOrigins {
+		v.Check(origin == "*" || validator.IsURL(origin), "cors-trusted-origins", fmt.Sprintf("%q must be * or an origin such as https://example.com", origin))
+	}
+
+	//с * любой сайт мог бы читать ответы от имени пользователя
+	if cfg.cors.allowCredentials {
+		v.Check(!slices.Contains(cfg.cors.trustedOrigins, "*"), "cors-allow-credentials", "must not be used with * in cors-trusted-origins, list the origins explicitly")
+	}
+
+	v.Check(validator.PermittedValue(cfg.traceExporter, "none", "stdout"), "trace-exporter", "must be none or stdout")
+	v.Check(cfg.metricsPath == "" || strings.HasPrefix(cfg.metricsPath, "/"), "metrics-path", "must start with /")
+	if cfg.metricsAddr != "" {
+		_, _, err := net.SplitHostPort(cfg.metricsAddr)
+		v.Check(err == nil, "metrics-addr", "must be a host:port address")
+	}
+
+	if v.Valid() {
+		return nil
+	}
+
+	keys := make([]string, 0, len(v.Errors))
+	for key := range v.Errors {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	problems := make([]string, 0, len(keys))
+	for _, key := range keys {
+		problems = append(problems, key+": "+v.Errors[key])
+	}
+
+	return errors.New("invalid configuration: " + strings.Join(problems, "; "))
+}
+
+// выводит итоговые настройки в формате файла -config (плоские ключи) без секретов
+func (cfg *config) print(w io.Writer) error {
+	var out yaml.MapSlice
+
+	cfg.flags.VisitAll(func(f *flag.Flag) {
+		if slices.Contains(commandLineOnlyFlags, f.Name) {
+			return
+		}
+
+		var value any = f.Value.String()
+
+		//числа и булевы значения выводятся без кавычек; длительности - строкой вида 15m0s
+		if getter, ok := f.Value.(flag.Getter); ok {
+			switch v := getter.Get().(type) {
+			case bool, int, float64:
+				value = v
+			}
+		}
+
+		switch {
+		case f.Name == "db-dsn":
+			value = redactDSN(f.Value.String())
+		case slices.Contains(secretFlags, f.Name) && f.Value.String() != "":
+			value = "[redacted]"
+		}
+
+		out = append(out, yaml.MapItem{Key: f.Name, Value: value})
+	})
+
+	content, err := yaml.Marshal(out)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(content)
+	return err
+}
+
+var dsnPasswordRX = regexp.MustCompile(`(password\s*=\s*)('(?:[^'\\]|\\.)*'|\S+)`)
+
+// строка подключения без пароля, в виде url или key=value
+func redactDSN(dsn string) string {
+	if u, err := url.Parse(dsn); err == nil && u.Scheme != "" {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), "redacted")
+		}
+		return u.String()
+	}
+
+	return dsnPasswordRX.ReplaceAllString(dsn, "${1}redacted")
+}
+
+// значение флага -cors-trusted-origins: источники через пробел или запятую
+type originList []string
+
+func (o *originList) String() string {
+	return strings.Join(*o, " ")
+}
+
+func (o *originList) Set(value string) error {
+	*o = strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+	return nil
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte(content), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func envFunc(env map[string]string) func(string) string {
+	return func(key string) string {
+		return env[key]
+	}
+}
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	path := writeConfigFile(t, `
+port: 4001
+db:
+  dsn: postgres://file@localhost/music
+limiter:
+  rps: 5
+  burst: 7
+mailer:
+  kind: file
+cors:
+  trusted-origins: [https://file.example.com]
+`)
+
+	env := map[string]string{
+		"MUSIC_PORT":        "4002",
+		"MUSIC_LIMITER_RPS": "9",
+		//пустая переменная не перекрывает файл
+		"MUSIC_LIMITER_BURST": "",
+	}
+
+	cfg, err := loadConfig([]string{"-config", path, "-port", "4003"}, envFunc(env))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		got  any
+		want any
+	}{
+		{"flag over env and file", cfg.port, 4003},
+		{"env over file", cfg.limiter.rps, 9.0},
+		{"file over default", cfg.limiter.burst, 7},
+		{"file only", cfg.db.dsn, "postgres://file@localhost/music"},
+		{"file alias", cfg.mailer.kind, "file"},
+		{"file list", cfg.cors.trustedOrigins.String(), "https://file.example.com"},
+		{"default", cfg.env, "development"},
+	}
+
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestLoadConfigFileFromEnv(t *testing.T) {
+	path := writeConfigFile(t, "db:\n  dsn: postgres://file@localhost/music\n")
+
+	cfg, err := loadConfig(nil, envFunc(map[string]string{"MUSIC_CONFIG": path}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.db.dsn != "postgres://file@localhost/music" {
+		t.Errorf("got dsn %q from MUSIC_CONFIG", cfg.db.dsn)
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		env     map[string]string
+		file    string
+		wantErr string
+	}{
+		{
+			name:    "missing dsn",
+			wantErr: "db-dsn: must be provided",
+		},
+		{
+			name:    "invalid env value names the variable",
+			env:     map[string]string{"MUSIC_DB_DSN": "postgres://localhost/music", "MUSIC_PORT": "http"},
+			wantErr: "MUSIC_PORT",
+		},
+		{
+			name:    "unknown file option",
+			args:    []string{"-db-dsn", "postgres://localhost/music"},
+			file:    "no-such-option: true\n",
+			wantErr: `unknown option "no-such-option"`,
+		},
+		{
+			name:    "command line only option in file",
+			args:    []string{"-db-dsn", "postgres://localhost/music"},
+			file:    "version: true\n",
+			wantErr: `unknown option "version"`,
+		},
+		{
+			name:    "several problems in one message",
+			args:    []string{"-port", "0", "-env", "prod"},
+			wantErr: "db-dsn: must be provided (flag -db-dsn, MUSIC_DB_DSN or db.dsn in the config file); env: must be development, staging or production; port: must be between 1 and 65535",
+		},
+		{
+			name:    "credentials with any origin",
+			args:    []string{"-db-dsn", "postgres://localhost/music", "-cors-trusted-origins", "*", "-cors-allow-credentials"},
+			wantErr: "cors-allow-credentials: must not be used with *",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := tt.args
+			if tt.file != "" {
+				args = append([]string{"-config", writeConfigFile(t, tt.file)}, args...)
+			}
+
+			_, err := loadConfig(args, envFunc(tt.env))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got error %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
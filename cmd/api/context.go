@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Segren/testTask/internal/data"
+)
+
+type contextKey string
+
+const (
+	userContextKey      = contextKey("user")
+	clientIPContextKey  = contextKey("client_ip")
+	routeContextKey     = contextKey("route")
+	requestIDContextKey = contextKey("request_id")
+	userIDContextKey    = contextKey("user_id")
+)
+
+// возвращает копию запроса с пользователем в контексте. Id пользователя также
+// записывается в место, подготовленное contextSetUserIDHolder
+func (app *application) contextSetUser(r *http.Request, user *data.User) *http.Request {
+	if id, ok := r.Context().Value(userIDContextKey).(*int64); ok && !user.IsAnonymous() {
+		*id = user.ID
+	}
+
+	ctx := context.WithValue(r.Context(), userContextKey, user)
+	return r.WithContext(ctx)
+}
+
+// возвращает копию запроса с местом под id пользователя, чтобы middleware, вызванные
+// до authenticate, видели пользователя после обработки запроса
+func (app *application) contextSetUserIDHolder(r *http.Request) *http.Request {
+	if _, ok := r.Context().Value(userIDContextKey).(*int64); ok {
+		return r
+	}
+
+	ctx := context.WithValue(r.Context(), userIDContextKey, new(int64))
+	return r.WithContext(ctx)
+}
+
+// id аутентифицированного пользователя или 0 для анонимного запроса и до authenticate
+func (app *application) contextGetUserID(r *http.Request) int64 {
+	if user, ok := r.Context().Value(userContextKey).(*data.User); ok && !user.IsAnonymous() {
+		return user.ID
+	}
+
+	if id, ok := r.Context().Value(userIDContextKey).(*int64); ok {
+		return *id
+	}
+
+	return 0
+}
+
+// пользователь из контекста. Вызывается только после middleware authenticate
+func (app *application) contextGetUser(r *http.Request) *data.User {
+	user, ok := r.Context().Value(userContextKey).(*data.User)
+	if !ok {
+		panic("missing user value in request context")
+	}
+
+	return user
+}
+
+const apiKeyContextKey = contextKey("api_key")
+
+// возвращает копию запроса с api-ключом, по которому аутентифицирован пользователь
+func (app *application) contextSetAPIKey(r *http.Request, key *data.APIKey) *http.Request {
+	ctx := context.WithValue(r.Context(), apiKeyContextKey, key)
+	return r.WithContext(ctx)
+}
+
+// api-ключ из контекста или nil, если запрос аутентифицирован иначе
+func (app *application) contextGetAPIKey(r *http.Request) *data.APIKey {
+	key, _ := r.Context().Value(apiKeyContextKey).(*data.APIKey)
+	return key
+}
+
+// возвращает копию запроса с адресом клиента
+func (app *application) contextSetClientIP(r *http.Request, ip string) *http.Request {
+	ctx := context.WithValue(r.Context(), clientIPContextKey, ip)
+	return r.WithContext(ctx)
+}
+
+// адрес клиента, определенный middleware resolveClientIP. Без него - адрес соединения
+func (app *application) contextGetClientIP(r *http.Request) string {
+	ip, ok := r.Context().Value(clientIPContextKey).(string)
+	if !ok {
+		return trustedProxies(nil).clientIP(r)
+	}
+
+	return ip
+}
+
+// возвращает копию запроса с местом под шаблон маршрута, который заполнит withRoute.
+// Если место уже есть, запрос возвращается без изменений
+func (app *application) contextSetRoute(r *http.Request) *http.Request {
+	if _, ok := r.Context().Value(routeContextKey).(*string); ok {
+		return r
+	}
+
+	ctx := context.WithValue(r.Context(), routeContextKey, new(string))
+	return r.WithContext(ctx)
+}
+
+// шаблон маршрута вида /songs/:id или "unmatched", если маршрут не найден
+func (app *application) contextGetRoute(r *http.Request) string {
+	route, ok := r.Context().Value(routeContextKey).(*string)
+	if !ok || *route == "" {
+		return "unmatched"
+	}
+
+	return *route
+}
+
+// запоминает шаблон маршрута в контексте запроса
+func (app *application) withRoute(pattern string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if route, ok := r.Context().Value(routeContextKey).(*string); ok {
+			*route = pattern
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
+
+// возвращает копию запроса с идентификатором запроса
+func (app *application) contextSetRequestID(r *http.Request, id string) *http.Request {
+	ctx := context.WithValue(r.Context(), requestIDContextKey, id)
+	return r.WithContext(ctx)
+}
+
+// идентификатор запроса или пустая строка, если middleware requestID не вызывался
+func (app *application) contextGetRequestID(r *http.Request) string {
+	id, _ := r.Context().Value(requestIDContextKey).(string)
+	return id
+}
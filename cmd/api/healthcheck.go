@@ -1,10 +1,51 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Segren/testTask/internal/provider"
 )
 
-func (app *application) healthcheckHandler(w http.ResponseWriter, r *http.Request) {
+// время на каждую проверку готовности
+const readinessCheckTimeout = 2 * time.Second
+
+// сколько результат проверок отдается без повторной проверки. /readyz публичный,
+// частые запросы не должны превращаться в запросы к бд и внешнему api
+const readinessCacheTTL = 2 * time.Second
+
+// результат проверки одной зависимости. Текст ошибки пишется только в журнал
+type dependencyStatus struct {
+	Status  string `json:"status"`
+	Latency string `json:"latency"`
+}
+
+// проверка зависимости. Сбой необязательной зависимости отмечается как degraded
+// и не делает сервер неготовым
+type readinessCheck struct {
+	name     string
+	check    func(context.Context) error
+	optional bool
+}
+
+// последние результаты проверок готовности
+type readinessCache struct {
+	mu      sync.Mutex
+	checked time.Time
+	ready   bool
+	results map[string]dependencyStatus
+}
+
+// @Summary Liveness probe
+// @Description Reports that the process is running. Dependencies are not checked.
+// @Tags monitoring
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Service is alive"
+// @Router /livez [get]
+func (app *application) livenessHandler(w http.ResponseWriter, r *http.Request) {
 	env := envelope{
 		"status": "available",
 		"system_info": map[string]string{
@@ -18,3 +59,124 @@ func (app *application) healthcheckHandler(w http.ResponseWriter, r *http.Reques
 		app.serverErrorResponse(w, r, err)
 	}
 }
+
+// @Summary Readiness probe
+// @Description Checks the database and the schema version and returns 503 if either fails or the server is shutting down. An unavailable info provider is reported as degraded without failing readiness. Results are cached for a few seconds.
+// @Tags monitoring
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Service is ready"
+// @Failure 503 {object} map[string]interface{} "Service is not ready"
+// @Router /readyz [get]
+func (app *application) readinessHandler(w http.ResponseWriter, r *http.Request) {
+	ready, results := app.checkReadiness()
+
+	status := http.StatusOK
+	env := envelope{"status": "ready", "checks": results}
+
+	if !ready {
+		status = http.StatusServiceUnavailable
+		env["status"] = "not ready"
+	}
+
+	//при остановке сервер перестает быть готовым, чтобы балансировщик увел с него трафик
+	if app.shuttingDown.Load() {
+		status = http.StatusServiceUnavailable
+		env["status"] = "shutting down"
+	}
+
+	err := app.writeJSON(w, status, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// проверяет зависимости параллельно или возвращает результаты, полученные меньше
+// readinessCacheTTL назад. Одновременные запросы ждут одну проверку
+func (app *application) checkReadiness() (bool, map[string]dependencyStatus) {
+	cache := &app.readiness
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if cache.results != nil && time.Since(cache.checked) < readinessCacheTTL {
+		return cache.ready, cache.results
+	}
+
+	checks := []readinessCheck{
+		{name: "database", check: app.models.Schema.Ping},
+		{name: "migrations", check: app.checkSchemaVersion},
+		{name: "provider", check: app.checkProvider, optional: true},
+	}
+
+	results := make(map[string]dependencyStatus, len(checks))
+	ready := true
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, c := range checks {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			//результат общий для всех запросов, поэтому проверка не зависит от контекста запроса
+			ctx, cancel := context.WithTimeout(context.Background(), readinessCheckTimeout)
+			defer cancel()
+
+			start := time.Now()
+			err := c.check(ctx)
+
+			result := dependencyStatus{Status: "up", Latency: time.Since(start).String()}
+			if err != nil {
+				app.logger.PrintError(fmt.Errorf("readiness check %s: %w", c.name, err), nil)
+
+				result.Status = "down"
+				if c.optional {
+					result.Status = "degraded"
+				}
+			}
+
+			mu.Lock()
+			results[c.name] = result
+			if result.Status == "down" {
+				ready = false
+			}
+			mu.Unlock()
+		}()
+	}
+
+	wg.Wait()
+
+	cache.checked = time.Now()
+	cache.ready = ready
+	cache.results = results
+
+	return ready, results
+}
+
+// проверяет, что миграции применены до версии, с которой работает код
+func (app *application) checkSchemaVersion(ctx context.Context) error {
+	version, dirty, err := app.models.Schema.Version(ctx)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case dirty:
+		return fmt.Errorf("migration %d failed and left the schema dirty", version)
+	case version < app.schemaVersion:
+		return fmt.Errorf("schema version %d is older than required %d", version, app.schemaVersion)
+	}
+
+	return nil
+}
+
+func (app *application) checkProvider(ctx context.Context) error {
+	p, ok := app.provider.(provider.Pinger)
+	if !ok {
+		return nil
+	}
+
+	return p.Ping(ctx)
+}
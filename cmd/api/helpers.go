@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/Segren/testTask/internal/data"
 	"github.com/Segren/testTask/internal/validator"
 	"github.com/julienschmidt/httprouter"
 	"io"
@@ -51,6 +52,40 @@ func (app *application) readInt(qs url.Values, key string, defaultValue int, v *
 	return i
 }
 
+// читает дату из строки запроса, пустое значение - невалидная (NULL) дата
+func (app *application) readDate(qs url.Values, key string, v *validator.Validator) data.Date {
+	s := qs.Get(key)
+
+	if s == "" {
+		return data.Date{}
+	}
+
+	d, err := data.ParseDate(s)
+	if err != nil {
+		v.AddError(key, "must be a date in YYYY, YYYY-MM or YYYY-MM-DD format")
+		return data.Date{}
+	}
+
+	return d
+}
+
+// читает булево значение из строки запроса, nil если ключ не задан
+func (app *application) readBool(qs url.Values, key string, v *validator.Validator) *bool {
+	s := qs.Get(key)
+
+	if s == "" {
+		return nil
+	}
+
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		v.AddError(key, "must be a boolean value")
+		return nil
+	}
+
+	return &b
+}
+
 // возвращает значения из строки запроса
 func (app *application) readString(qs url.Values, key string, defaultValue string) string {
 	//получить сначение из строки для заданного ключа. Если ключа в строке не существует - вернет пустую строку
@@ -63,6 +98,9 @@ func (app *application) readString(qs url.Values, key string, defaultValue strin
 	return s
 }
 
+// тело запроса пустое, обработчики с необязательным телом проверяют его через errors.Is
+var errEmptyBody = errors.New("body must not be empty")
+
 func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst interface{}) error {
 	maxBytes := 1_048_576
 	r.Body = http.MaxBytesReader(w, r.Body, int64(maxBytes))
@@ -87,7 +125,7 @@ func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst int
 			}
 			return fmt.Errorf("body contains incorrect JSON type (at character %d)", unmarshalTypeError.Offset)
 		case errors.Is(err, io.EOF):
-			return errors.New("body must not be empty")
+			return errEmptyBody
 		case strings.HasPrefix(err.Error(), "json: unknown field "):
 			fieldName := strings.TrimPrefix(err.Error(), "json: unknown field")
 			return fmt.Errorf("body contains unknown key %s", fieldName)
@@ -109,12 +147,55 @@ func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst int
 }
 
 func (app *application) readIDParam(r *http.Request) (int64, error) {
+	return app.readInt64Param(r, "id")
+}
+
+// читает положительный int64 параметр маршрута
+func (app *application) readInt64Param(r *http.Request, name string) (int64, error) {
 	params := httprouter.ParamsFromContext(r.Context())
 
-	id, err := strconv.ParseInt(params.ByName("id"), 10, 64)
+	id, err := strconv.ParseInt(params.ByName(name), 10, 64)
 	if err != nil || id < 1 {
-		return 0, errors.New("invalid id parameter")
-
+		return 0, fmt.Errorf("invalid %s parameter", name)
 	}
 	return id, nil
 }
+
+// проверяет что песня из параметра маршрута существует. При ошибке ответ уже отправлен
+func (app *application) readExistingSongID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return 0, false
+	}
+
+	_, err = app.models.Songs.Get(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return 0, false
+	}
+
+	return id, true
+}
+
+// запускает функцию в фоне, wg позволяет дождаться ее завершения при остановке сервера
+func (app *application) background(fn func()) {
+	app.wg.Add(1)
+
+	go func() {
+		defer app.wg.Done()
+
+		defer func() {
+			if err := recover(); err != nil {
+				app.logger.PrintError(fmt.Errorf("%s", err), nil)
+			}
+		}()
+
+		fn()
+	}()
+}
@@ -0,0 +1,352 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Segren/testTask/internal/data"
+	"github.com/Segren/testTask/internal/validator"
+)
+
+// @Summary Get favorite songs
+// @Tags library
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number"
+// @Param page_size query int false "Number of items per page"
+// @Param sort query string false "Sort order ('favorited_at' or '-favorited_at')"
+// @Success 200 {object} data.SongsResponse "Favorite songs with metadata"
+// @Failure 401 {string} string "Authentication required"
+// @Failure 403 {string} string "Account not activated"
+// @Failure 422 {string} string "Validation error"
+// @Failure 500 {string} string "Internal server error"
+// @Router /me/favorites [get]
+func (app *application) listFavoritesHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	var filters data.Filters
+
+	filters.Page = app.readInt(qs, "page", 1, v)
+	filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	filters.Sort = app.readString(qs, "sort", "-favorited_at")
+
+	filters.SortSafelist = []string{"favorited_at", "-favorited_at"}
+
+	if data.ValidateFilters(v, filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	songs, metadata, err := app.models.Favorites.GetAllForUser(user.ID, filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"songs": songs, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// @Summary Add a song to favorites
+// @Tags library
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Song ID"
+// @Success 200 {string} string "Message indicating the song is in favorites"
+// @Failure 401 {string} string "Authentication required"
+// @Failure 403 {string} string "Account not activated"
+// @Failure 404 {string} string "Song not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /me/favorites/{id} [put]
+func (app *application) addFavoriteHandler(w http.ResponseWriter, r *http.Request) {
+	songID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	err = app.models.Favorites.Add(user.ID, songID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "song added to favorites"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// @Summary Remove a song from favorites
+// @Tags library
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Song ID"
+// @Success 200 {string} string "Message indicating successful removal"
+// @Failure 401 {string} string "Authentication required"
+// @Failure 403 {string} string "Account not activated"
+// @Failure 404 {string} string "Song not in favorites"
+// @Failure 500 {string} string "Internal server error"
+// @Router /me/favorites/{id} [delete]
+func (app *application) removeFavoriteHandler(w http.ResponseWriter, r *http.Request) {
+	songID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	err = app.models.Favorites.Remove(user.ID, songID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "song removed from favorites"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// @Summary Get my ratings
+// @Tags library
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} data.Rating "Ratings of the current user"
+// @Failure 401 {string} string "Authentication required"
+// @Failure 403 {string} string "Account not activated"
+// @Failure 500 {string} string "Internal server error"
+// @Router /me/ratings [get]
+func (app *application) listRatingsHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	ratings, err := app.models.Ratings.GetAllForUser(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"ratings": ratings}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// @Summary Rate a song
+// @Description Rate a song from 1 to 5. A repeated rating replaces the previous one.
+// @Tags library
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Song ID"
+// @Param rating body data.Rating true "Rating from 1 to 5"
+// @Success 200 {object} data.Rating "Stored rating"
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Authentication required"
+// @Failure 403 {string} string "Account not activated"
+// @Failure 404 {string} string "Song not found"
+// @Failure 422 {string} string "Validation error"
+// @Failure 500 {string} string "Internal server error"
+// @Router /me/ratings/{id} [put]
+func (app *application) rateSongHandler(w http.ResponseWriter, r *http.Request) {
+	songID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Rating int `json:"rating"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if data.ValidateRating(v, input.Rating); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	rating := &data.Rating{
+		SongID: songID,
+		Rating: input.Rating,
+	}
+
+	user := app.contextGetUser(r)
+
+	err = app.models.Ratings.Upsert(user.ID, rating)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"rating": rating}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// @Summary Delete my rating of a song
+// @Tags library
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Song ID"
+// @Success 200 {string} string "Message indicating successful deletion"
+// @Failure 401 {string} string "Authentication required"
+// @Failure 403 {string} string "Account not activated"
+// @Failure 404 {string} string "Rating not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /me/ratings/{id} [delete]
+func (app *application) deleteRatingHandler(w http.ResponseWriter, r *http.Request) {
+	songID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	err = app.models.Ratings.Delete(user.ID, songID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "rating successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// @Summary Get my listening history
+// @Tags library
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number"
+// @Param page_size query int false "Number of items per page"
+// @Success 200 {array} data.Play "Play events, most recent first"
+// @Failure 401 {string} string "Authentication required"
+// @Failure 403 {string} string "Account not activated"
+// @Failure 422 {string} string "Validation error"
+// @Failure 500 {string} string "Internal server error"
+// @Router /me/plays [get]
+func (app *application) listPlaysHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	var filters data.Filters
+
+	filters.Page = app.readInt(qs, "page", 1, v)
+	filters.PageSize = app.readInt(qs, "page_size", 20, v)
+
+	//история всегда отдается начиная с последних прослушиваний
+	filters.Sort = "-played_at"
+	filters.SortSafelist = []string{"-played_at"}
+
+	if data.ValidateFilters(v, filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	plays, metadata, err := app.models.Plays.GetAllForUser(user.ID, filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"plays": plays, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// @Summary Log a play
+// @Description Record that the current user listened to a song. Play counts are shown on song responses.
+// @Tags library
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param play body data.Play true "Song ID"
+// @Success 201 {object} data.Play "Recorded play event"
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Authentication required"
+// @Failure 403 {string} string "Account not activated"
+// @Failure 422 {string} string "Validation error"
+// @Failure 500 {string} string "Internal server error"
+// @Router /me/plays [post]
+func (app *application) createPlayHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		SongID int64 `json:"song_id"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if v.Check(input.SongID > 0, "song_id", "must be a positive integer"); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	play := &data.Play{SongID: input.SongID}
+
+	user := app.contextGetUser(r)
+
+	err = app.models.Plays.Insert(user.ID, play)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("song_id", "song does not exist")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"play": play}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Segren/testTask/internal/data"
+)
+
+// фоновая проверка ссылок песен HEAD запросами. Работает до отмены ctx
+func (app *application) startLinkChecker(ctx context.Context) {
+	if !app.config.linkChecker.enabled {
+		return
+	}
+
+	app.background(func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+
+		for {
+			app.checkLinks(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+// проверяет пачку давно не проверявшихся ссылок пулом из linkChecker.workers горутин
+func (app *application) checkLinks(ctx context.Context) {
+	cfg := app.config.linkChecker
+
+	links, err := app.models.Links.GetDueForCheck(ctx, cfg.interval, 100)
+	if err != nil {
+		app.logger.PrintError(err, nil)
+		return
+	}
+
+	client := newLinkCheckClient(cfg.timeout)
+	jobs := make(chan *data.Link)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < cfg.workers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for link := range jobs {
+				ok := checkLink(ctx, client, link.URL)
+
+				//проверка прервана остановкой сервера, а не недоступностью ссылки
+				if ctx.Err() != nil {
+					continue
+				}
+
+				err := app.models.Links.RecordCheck(ctx, link.ID, ok, cfg.maxFailures)
+				if err != nil {
+					app.logger.PrintError(err, nil)
+				}
+			}
+		}()
+	}
+
+send:
+	for _, link := range links {
+		select {
+		case jobs <- link:
+		case <-ctx.Done():
+			break send
+		}
+	}
+
+	close(jobs)
+	wg.Wait()
+}
+
+// ссылка жива если отвечает кодом < 400. Сервера без поддержки HEAD проверяются через GET
+func checkLink(ctx context.Context, client *http.Client, url string) bool {
+	status, err := requestStatus(ctx, client, http.MethodHead, url)
+	if err == nil && (status == http.StatusMethodNotAllowed || status == http.StatusNotImplemented) {
+		status, err = requestStatus(ctx, client, http.MethodGet, url)
+	}
+
+	return err == nil && status < http.StatusBadRequest
+}
+
+func requestStatus(ctx context.Context, client *http.Client, method, url string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	return resp.StatusCode, nil
+}
+
+var errForbiddenAddress = errors.New("destination address is not public")
+
+// сети, кроме внутренних адресов из netip, в которые проверка ссылок не ходит
+var forbiddenPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+}
+
+// ссылки задают пользователи, поэтому клиент проверки ходит только на публичные адреса.
+// Адрес проверяется после разрешения имени при каждом подключении, в т.ч. после редиректа,
+// а прокси из окружения не используется, чтобы проверялся адрес самой ссылки
+func newLinkCheckClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: denyInternalAddress,
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Proxy:               nil,
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			DisableKeepAlives:   true,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 5 {
+				return errors.New("stopped after 5 redirects")
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return fmt.Errorf("redirect to unsupported scheme %q", req.URL.Scheme)
+			}
+			return nil
+		},
+	}
+}
+
+// net.Dialer.Control: отклоняет подключения к loopback, частным, link-local и прочим
+// непубличным адресам
+func denyInternalAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+
+	if !publicAddress(ip) {
+		return fmt.Errorf("%w: %s", errForbiddenAddress, ip)
+	}
+
+	return nil
+}
+
+func publicAddress(ip netip.Addr) bool {
+	ip = ip.Unmap()
+
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+
+	for _, prefix := range forbiddenPrefixes {
+		if prefix.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
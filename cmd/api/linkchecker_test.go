@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestPublicAddress(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fc00::1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"100.64.0.1", false},
+		{"::ffff:127.0.0.1", false},
+		{"224.0.0.1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			got := publicAddress(netip.MustParseAddr(tt.addr))
+			if got != tt.want {
+				t.Errorf("publicAddress(%s) = %t, want %t", tt.addr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLinkCheckClientRejectsInternalAddresses(t *testing.T) {
+	var hits int
+
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+	}))
+	defer internal.Close()
+
+	client := newLinkCheckClient(time.Second)
+
+	_, err := requestStatus(context.Background(), client, http.MethodHead, internal.URL)
+	if !errors.Is(err, errForbiddenAddress) {
+		t.Fatalf("got error %v, want %v", err, errForbiddenAddress)
+	}
+
+	if hits != 0 {
+		t.Fatalf("internal server received %d requests", hits)
+	}
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/Segren/testTask/internal/data"
+	"github.com/Segren/testTask/internal/validator"
+)
+
+// @Summary Get song links
+// @Description Retrieve external links of a song with optional filters by type and health
+// @Tags links
+// @Produce json
+// @Param id path int true "Song ID"
+// @Param type query string false "Filter by link type"
+// @Param dead query bool false "Filter by dead links"
+// @Success 200 {array} data.Link "Links of the song"
+// @Failure 404 {string} string "Song not found"
+// @Failure 422 {string} string "Validation error"
+// @Failure 500 {string} string "Internal server error"
+// @Router /songs/{id}/links [get]
+func (app *application) listSongLinksHandler(w http.ResponseWriter, r *http.Request) {
+	songID, ok := app.readExistingSongID(w, r)
+	if !ok {
+		return
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	linkType := app.readString(qs, "type", "")
+	dead := app.readBool(qs, "dead", v)
+
+	if linkType != "" {
+		v.Check(validator.PermittedValue(linkType, data.LinkTypes...), "type", "must be one of the supported link types")
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	links, err := app.models.Links.GetAllForSong(r.Context(), songID, linkType, dead)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"links": links}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// @Summary Add a song link
+// @Description Add an external link (YouTube, Spotify, lyrics page, etc.) to a song
+// @Tags links
+// @Accept json
+// @Produce json
+// @Param id path int true "Song ID"
+// @Param link body data.Link true "Link type and URL"
+// @Success 201 {object} data.Link "The newly created link"
+// @Failure 400 {string} string "Bad request"
+// @Failure 404 {string} string "Song not found"
+// @Failure 422 {string} string "Validation error"
+// @Failure 500 {string} string "Internal server error"
+// @Security BearerAuth
+// @Failure 401 {string} string "Authentication required"
+// @Failure 403 {string} string "Account not activated or not permitted"
+// @Router /songs/{id}/links [post]
+func (app *application) createSongLinkHandler(w http.ResponseWriter, r *http.Request) {
+	songID, ok := app.readExistingSongID(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Type string `json:"type"`
+		URL  string `json:"url"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	link := &data.Link{
+		SongID:  songID,
+		Type:    input.Type,
+		URL:     input.URL,
+		AddedBy: app.contextGetUser(r).Name,
+	}
+
+	v := validator.New()
+
+	if data.ValidateLink(v, link); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Links.Insert(r.Context(), link)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateLink):
+			v.AddError("url", "a link with this url already exists for the song")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/songs/%d/links/%d", songID, link.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"link": link}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// @Summary Get a song link
+// @Tags links
+// @Produce json
+// @Param id path int true "Song ID"
+// @Param link_id path int true "Link ID"
+// @Success 200 {object} data.Link "Link"
+// @Failure 404 {string} string "Link not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /songs/{id}/links/{link_id} [get]
+func (app *application) showSongLinkHandler(w http.ResponseWriter, r *http.Request) {
+	link, ok := app.readLink(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.writeJSON(w, http.StatusOK, envelope{"link": link}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// @Summary Update a song link
+// @Description Change the type or URL of a link. Changing the URL resets its health status.
+// @Tags links
+// @Accept json
+// @Produce json
+// @Param id path int true "Song ID"
+// @Param link_id path int true "Link ID"
+// @Param link body data.Link true "Fields to update"
+// @Success 200 {object} data.Link "Updated link"
+// @Failure 400 {string} string "Bad request"
+// @Failure 404 {string} string "Link not found"
+// @Failure 409 {string} string "Edit conflict occurred"
+// @Failure 422 {string} string "Validation error"
+// @Failure 500 {string} string "Internal server error"
+// @Security BearerAuth
+// @Failure 401 {string} string "Authentication required"
+// @Failure 403 {string} string "Account not activated or not permitted"
+// @Router /songs/{id}/links/{link_id} [patch]
+func (app *application) updateSongLinkHandler(w http.ResponseWriter, r *http.Request) {
+	link, ok := app.readLink(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Type *string `json:"type"`
+		URL  *string `json:"url"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Type != nil {
+		link.Type = *input.Type
+	}
+	//новый адрес еще не проверялся
+	if input.URL != nil && *input.URL != link.URL {
+		link.URL = *input.URL
+		link.FailureCount = 0
+		link.Dead = false
+	}
+
+	v := validator.New()
+
+	if data.ValidateLink(v, link); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Links.Update(r.Context(), link)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		case errors.Is(err, data.ErrDuplicateLink):
+			v.AddError("url", "a link with this url already exists for the song")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"link": link}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// @Summary Delete a song link
+// @Tags links
+// @Produce json
+// @Param id path int true "Song ID"
+// @Param link_id path int true "Link ID"
+// @Success 200 {string} string "Message indicating successful deletion"
+// @Failure 404 {string} string "Link not found"
+// @Failure 500 {string} string "Internal server error"
+// @Security BearerAuth
+// @Failure 401 {string} string "Authentication required"
+// @Failure 403 {string} string "Account not activated or not permitted"
+// @Router /songs/{id}/links/{link_id} [delete]
+func (app *application) deleteSongLinkHandler(w http.ResponseWriter, r *http.Request) {
+	songID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	linkID, err := app.readInt64Param(r, "link_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Links.Delete(r.Context(), songID, linkID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "link successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// ссылка из параметров маршрута. При ошибке ответ уже отправлен
+func (app *application) readLink(w http.ResponseWriter, r *http.Request) (*data.Link, bool) {
+	songID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	linkID, err := app.readInt64Param(r, "link_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	link, err := app.models.Links.Get(r.Context(), songID, linkID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	return link, true
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Segren/testTask/internal/jsonlog"
+	"github.com/Segren/testTask/internal/tracing"
+	"github.com/Segren/testTask/internal/validator"
+)
+
+// логгер запроса: каждая запись содержит request_id, trace_id, span_id и user_id, если они известны
+func (app *application) requestLogger(r *http.Request) *jsonlog.Logger {
+	fields := make(map[string]string, 4)
+
+	if id := app.contextGetRequestID(r); id != "" {
+		fields["request_id"] = id
+	}
+
+	if span := tracing.SpanFromContext(r.Context()); span != nil {
+		sc := span.SpanContext()
+		fields["trace_id"] = sc.TraceID.String()
+		fields["span_id"] = sc.SpanID.String()
+	}
+
+	//логгер нужен и до middleware authenticate, поэтому contextGetUser не подходит
+	if id := app.contextGetUserID(r); id != 0 {
+		fields["user_id"] = strconv.FormatInt(id, 10)
+	}
+
+	if len(fields) == 0 {
+		return app.logger
+	}
+
+	return app.logger.With(fields)
+}
+
+// @Summary Get log level
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {string} string "Current minimum log level"
+// @Failure 401 {string} string "Authentication required"
+// @Failure 403 {string} string "Not permitted"
+// @Failure 500 {string} string "Internal server error"
+// @Router /admin/log-level [get]
+func (app *application) showLogLevelHandler(w http.ResponseWriter, r *http.Request) {
+	err := app.writeJSON(w, http.StatusOK, envelope{"level": strings.ToLower(app.logger.Level().String())}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// @Summary Change log level
+// @Description Change the minimum log level until restart (debug, info, warn, error, fatal or off).
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param level body object true "New log level"
+// @Success 200 {string} string "New minimum log level"
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Authentication required"
+// @Failure 403 {string} string "Not permitted"
+// @Failure 422 {string} string "Validation error"
+// @Failure 500 {string} string "Internal server error"
+// @Router /admin/log-level [put]
+func (app *application) updateLogLevelHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Level string `json:"level"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	level, err := jsonlog.ParseLevel(input.Level)
+	if v.Check(err == nil, "level", "must be one of debug, info, warn, error, fatal, off"); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	previous := app.logger.Level()
+	app.logger.SetLevel(level)
+
+	app.requestLogger(r).PrintWarn("log level changed", map[string]string{
+		"from": strings.ToLower(previous.String()),
+		"to":   strings.ToLower(level.String()),
+	})
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"level": strings.ToLower(level.String())}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
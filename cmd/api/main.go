@@ -3,6 +3,10 @@
 // @description API for managing music library
 // @host localhost:8080
 // @BasePath /
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description Bearer authentication token, e.g. "Bearer <token>"
 package main
 
 import (
@@ -10,16 +14,27 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"expvar"
 	"flag"
 	"fmt"
 	_ "github.com/lib/pq"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/Segren/testTask/internal/data"
 	"github.com/Segren/testTask/internal/jsonlog"
+	"github.com/Segren/testTask/internal/mailer"
+	"github.com/Segren/testTask/internal/migrate"
+	"github.com/Segren/testTask/internal/provider"
+	"github.com/Segren/testTask/internal/ratelimit"
+	"github.com/Segren/testTask/internal/tracing"
+	"github.com/Segren/testTask/migrations"
 
 	_ "github.com/Segren/testTask/cmd/api/docs"
 )
@@ -29,63 +44,40 @@ var (
 	version   string
 )
 
-type config struct {
-	port int
-	env  string
-	db   struct {
-		dsn          string
-		maxOpenConns int
-		maxIdleConns int
-		maxIdleTime  string
-	}
-	//для конфигурации кол-ва запросов в секунду и во время бурста
-	limiter struct {
-		rps     float64
-		burst   int
-		enabled bool
-	}
-	displayVersion bool
-}
-
-var (
-	instance *config
-	once     sync.Once
-)
-
-// singleton
-func GetConfig() *config {
-	once.Do(func() {
-		instance = &config{}
-		flag.IntVar(&instance.port, "port", 8080, "API server port")
-		flag.StringVar(&instance.env, "env", "development", "Environment (development|staging|production)")
-
-		flag.StringVar(&instance.db.dsn, "db-dsn", "", "PostgreSQL DSN")
-
-		flag.IntVar(&instance.db.maxOpenConns, "db-max-open-conns", 25, "PostgreSQL max open connections")
-		flag.IntVar(&instance.db.maxIdleConns, "db-max-idle-conns", 25, "PostgreSQL max idle connections")
-		flag.StringVar(&instance.db.maxIdleTime, "db-max-idle-time", "15m", "PostgreSQL max connection idle time")
-
-		flag.Float64Var(&instance.limiter.rps, "limiter-rps", 20, "Rate limiter maximum requests per second")
-		flag.IntVar(&instance.limiter.burst, "limiter-burst", 50, "Rate limiter maximum burst")
-		flag.BoolVar(&instance.limiter.enabled, "limiter-enabled", true, "Enable rate limiter")
-
-		// булево для отображения версии проекта и выхода
-		flag.BoolVar(&instance.displayVersion, "version", false, "Display version information and exit")
-
-		flag.Parse()
-	})
-	return instance
-}
-
 type application struct {
-	config config
-	logger *jsonlog.Logger
-	models data.Models
-	wg     sync.WaitGroup
+	config   config
+	logger   *jsonlog.Logger
+	tracer   *tracing.Tracer
+	models   data.Models
+	provider provider.Fetcher
+	mailer   mailer.Mailer
+	limiter  ratelimit.Store
+	metrics  *appMetrics
+	wg       sync.WaitGroup
+	//последние примененные настройки, которые меняются по SIGHUP
+	live atomic.Pointer[config]
+	//клиент внешнего api за кешем, его адрес меняется по SIGHUP
+	providerClient *provider.Client
+	//кеш ответов внешнего api, сбрасывается при смене адреса
+	providerCache *provider.Cache
+	//версия схемы, с которой работает код (последняя встроенная миграция)
+	schemaVersion int64
+	//сервер останавливается, /readyz отвечает 503
+	shuttingDown atomic.Bool
+	//результаты проверок /readyz
+	readiness readinessCache
+	//сигналы SIGHUP, перехваченные с запуска процесса
+	hup chan os.Signal
 }
 
 func main() {
-	cfg := GetConfig()
+	cfg, err := loadConfig(os.Args[1:], os.Getenv)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		jsonlog.New(os.Stdout, jsonlog.LevelInfo).PrintFatal(err, nil)
+	}
 
 	if cfg.displayVersion {
 		fmt.Printf("Greenlight version:\t%s\n", version)
@@ -93,16 +85,24 @@ func main() {
 		os.Exit(0)
 	}
 
-	logger := jsonlog.New(os.Stdout, jsonlog.LevelInfo)
-
-	if cfg.db.dsn == "" {
-		cfg.db.dsn = os.Getenv("MUSIC_DB_DSN")
-		if cfg.db.dsn == "" {
-			logger.PrintFatal(errors.New("должна быть установлена строка подключения к базе данных через флаг -db-dsn или переменную окружения MUSIC_DB_DSN"), nil)
+	if cfg.printConfig {
+		err = cfg.print(os.Stdout)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
 		}
+		os.Exit(0)
 	}
 
-	logger.PrintInfo("Connecting to database with DSN: "+cfg.db.dsn, nil)
+	logger := jsonlog.New(os.Stdout, cfg.log.level)
+	logger.SetStackTraces(cfg.log.stackTraces)
+
+	//SIGHUP по умолчанию завершает процесс, поэтому он перехватывается до подключения к бд
+	//и миграций. Сигнал, пришедший до запуска сервера, применяется в serve
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	logger.PrintInfo("Connecting to database with DSN: "+redactDSN(cfg.db.dsn), nil)
 
 	db, err := openDB(*cfg)
 	if err != nil {
@@ -113,14 +113,131 @@ func main() {
 
 	logger.PrintInfo("database connection pool established", nil)
 
+	migrator, err := migrate.New(db, migrations.FS)
+	if err != nil {
+		logger.PrintFatal(err, nil)
+	}
+	migrator.Log = func(direction string, m *migrate.Migration) {
+		logger.PrintInfo("migration applied", map[string]string{
+			"direction": direction,
+			"version":   strconv.FormatInt(m.Version, 10),
+			"name":      m.Name,
+		})
+	}
+
+	//api migrate ... - подкоманда вместо запуска сервера
+	if args := cfg.flags.Args(); len(args) > 0 {
+		if args[0] != "migrate" {
+			logger.PrintFatal(fmt.Errorf("unknown command %q: %s", args[0], migrateUsage), nil)
+		}
+
+		err = runMigrate(migrator, logger, os.Stdout, args[1:])
+		if err != nil {
+			logger.PrintFatal(err, nil)
+		}
+		return
+	}
+
+	models := data.NewModels(db)
+
+	var store provider.Store
+	if cfg.provider.cache.persistent {
+		store = models.ProviderCache
+	}
+
+	providerClient := provider.NewClient(cfg.provider.url, cfg.provider.timeout)
+
+	cache := provider.NewCache(
+		providerClient,
+		store,
+		cfg.provider.cache.size,
+		cfg.provider.cache.ttl,
+		cfg.provider.cache.negativeTTL,
+	)
+	cache.SetScope(cfg.provider.url)
+	cache.OnStoreError(func(err error) {
+		logger.PrintWarn(err.Error(), nil)
+	})
+
+	if cfg.provider.cache.persistent {
+		//просроченные ответы удаляем раз в 10 минут, чтобы таблица не росла бесконечно
+		go func() {
+			for {
+				time.Sleep(10 * time.Minute)
+
+				n, err := models.ProviderCache.DeleteExpired()
+				if err != nil {
+					logger.PrintWarn(fmt.Sprintf("provider cache cleanup: %v", err), nil)
+					continue
+				}
+				logger.PrintDebug("provider cache cleanup", map[string]string{"deleted": strconv.FormatInt(n, 10)})
+			}
+		}()
+	}
+
+	//статистика кеша доступна в /debug/vars
+	expvar.Publish("provider_cache", expvar.Func(func() any {
+		return cache.Stats()
+	}))
+
+	sender, err := newMailSender(*cfg, logger)
+	if err != nil {
+		logger.PrintFatal(err, nil)
+	}
+
+	var limiter ratelimit.Store
+	switch cfg.limiter.store {
+	case "memory":
+		limiter = ratelimit.NewMemory()
+	case "postgres":
+		limiter = ratelimit.NewPostgres(db)
+	default:
+		logger.PrintFatal(fmt.Errorf("unknown rate limiter store %q", cfg.limiter.store), nil)
+	}
+
+	//трассировка идет всегда, чтобы trace_id попадал в журнал и во внешнее api, выгрузка - по флагу
+	var exporter tracing.Exporter
+	switch cfg.traceExporter {
+	case "none":
+	case "stdout":
+		exporter = tracing.NewJSONExporter(os.Stdout)
+	default:
+		logger.PrintFatal(fmt.Errorf("unknown trace exporter %q", cfg.traceExporter), nil)
+	}
+
+	tracer := tracing.New(exporter, func(err error) {
+		logger.PrintError(fmt.Errorf("export span: %w", err), nil)
+	})
+
 	app := &application{
-		config: *cfg,
-		logger: logger,
-		models: data.NewModels(db),
+		config:         *cfg,
+		logger:         logger,
+		tracer:         tracer,
+		models:         models,
+		provider:       cache,
+		providerClient: providerClient,
+		providerCache:  cache,
+		mailer:         mailer.New(sender, cfg.mailer.sender),
+		limiter:        limiter,
+		metrics:        newMetrics(db, cache),
+		hup:            hup,
+	}
+
+	app.live.Store(cfg)
+
+	app.schemaVersion = migrator.Latest()
+
+	err = app.checkSchema(migrator)
+	if err != nil {
+		logger.PrintFatal(err, nil)
 	}
 
-	//запускаем мок внешнего api чтобы получать releaseDate, text, link
-	startExternalMockServer()
+	app.metrics.registry.PublishExpvar()
+
+	//мок внешнего api чтобы получать releaseDate, text, link, только для разработки
+	if cfg.env == "development" {
+		startExternalMockServer()
+	}
 
 	err = app.serve()
 	if err != nil {
@@ -156,8 +273,24 @@ func openDB(cfg config) (*sql.DB, error) {
 	return db, nil
 }
 
+// способ доставки писем из конфигурации
+func newMailSender(cfg config, logger *jsonlog.Logger) (mailer.Sender, error) {
+	switch cfg.mailer.kind {
+	case "log":
+		return mailer.NewLogSender(logger), nil
+	case "file":
+		return mailer.NewFileSender(cfg.mailer.dir)
+	case "smtp":
+		return mailer.NewSMTPSender(cfg.mailer.smtp.host, cfg.mailer.smtp.port, cfg.mailer.smtp.username, cfg.mailer.smtp.password), nil
+	default:
+		return nil, fmt.Errorf("unknown mailer %q", cfg.mailer.kind)
+	}
+}
+
 func startExternalMockServer() {
-	http.HandleFunc("/info", func(w http.ResponseWriter, r *http.Request) {
+	//свой mux, чтобы на порт мока не попали /debug/vars и прочие глобальные обработчики
+	mux := http.NewServeMux()
+	mux.HandleFunc("/info", func(w http.ResponseWriter, r *http.Request) {
 		group := r.URL.Query().Get("group")
 		song := r.URL.Query().Get("song")
 
@@ -167,7 +300,7 @@ func startExternalMockServer() {
 		}
 
 		response := map[string]interface{}{
-			"releaseDate": "2024-11-22",
+			"releaseDate": "22.11.2024",
 			"text":        "Some lyrics here",
 			"link":        "https://example.com/song",
 		}
@@ -175,5 +308,5 @@ func startExternalMockServer() {
 		json.NewEncoder(w).Encode(response)
 	})
 
-	go http.ListenAndServe(":8081", nil)
+	go http.ListenAndServe("127.0.0.1:8081", mux)
 }
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/Segren/testTask/internal/metrics"
+	"github.com/Segren/testTask/internal/provider"
+)
+
+// метрики http запросов и ресурсов приложения
+type appMetrics struct {
+	registry  *metrics.Registry
+	requests  *metrics.Counter
+	responses *metrics.CounterVec
+	inFlight  *metrics.Gauge
+	duration  *metrics.HistogramVec
+}
+
+func newMetrics(db *sql.DB, cache *provider.Cache) *appMetrics {
+	r := metrics.NewRegistry()
+
+	m := &appMetrics{
+		registry:  r,
+		requests:  r.Counter("http_requests_total", "Total number of HTTP requests received."),
+		responses: r.CounterVec("http_responses_total", "Total number of HTTP responses sent by status code.", "status"),
+		inFlight:  r.Gauge("http_requests_in_flight", "Number of HTTP requests being processed."),
+		duration:  r.HistogramVec("http_request_duration_seconds", "HTTP request latency by route.", metrics.DefaultBuckets, "method", "route"),
+	}
+
+	r.GaugeFunc("go_goroutines", "Number of goroutines.", func() float64 {
+		return float64(runtime.NumGoroutine())
+	})
+
+	//статистика пула подключений к бд
+	r.GaugeFunc("db_open_connections", "Number of established database connections.", func() float64 {
+		return float64(db.Stats().OpenConnections)
+	})
+	r.GaugeFunc("db_in_use_connections", "Number of database connections in use.", func() float64 {
+		return float64(db.Stats().InUse)
+	})
+	r.GaugeFunc("db_idle_connections", "Number of idle database connections.", func() float64 {
+		return float64(db.Stats().Idle)
+	})
+	r.CounterFunc("db_wait_count_total", "Total number of waits for a database connection.", func() float64 {
+		return float64(db.Stats().WaitCount)
+	})
+	r.CounterFunc("db_wait_duration_seconds_total", "Total time spent waiting for a database connection.", func() float64 {
+		return db.Stats().WaitDuration.Seconds()
+	})
+
+	//статистика кеша внешнего api
+	r.CounterFunc("provider_cache_hits_total", "Info provider responses served from memory.", func() float64 {
+		return float64(cache.Stats().Hits)
+	})
+	r.CounterFunc("provider_cache_negative_hits_total", "Info provider not found responses served from memory.", func() float64 {
+		return float64(cache.Stats().NegativeHits)
+	})
+	r.CounterFunc("provider_cache_misses_total", "Info provider requests that missed the cache.", func() float64 {
+		return float64(cache.Stats().Misses)
+	})
+	r.CounterFunc("provider_cache_store_hits_total", "Info provider responses served from the persistent cache.", func() float64 {
+		return float64(cache.Stats().StoreHits)
+	})
+	r.CounterFunc("provider_cache_store_errors_total", "Persistent provider cache read and write errors.", func() float64 {
+		return float64(cache.Stats().StoreErrors)
+	})
+	r.GaugeFunc("provider_cache_entries", "Info provider responses held in memory.", func() float64 {
+		return float64(cache.Stats().Entries)
+	})
+
+	return m
+}
+
+// записывает статус и размер ответа для метрик и логов
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int
+	wroteHeader bool
+}
+
+func (rw *responseRecorder) WriteHeader(status int) {
+	if !rw.wroteHeader {
+		rw.status = status
+		rw.wroteHeader = true
+	}
+
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *responseRecorder) Write(b []byte) (int, error) {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytes += n
+
+	return n, err
+}
+
+// для http.ResponseController
+func (rw *responseRecorder) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// считает запросы, ответы по кодам и время обработки по маршрутам
+func (app *application) recordMetrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		app.metrics.requests.Inc()
+		app.metrics.inFlight.Inc()
+		defer app.metrics.inFlight.Dec()
+
+		rw := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		r = app.contextSetRoute(r)
+
+		next.ServeHTTP(rw, r)
+
+		app.metrics.responses.WithLabelValues(strconv.Itoa(rw.status)).Inc()
+		app.metrics.duration.WithLabelValues(methodLabel(r.Method), app.contextGetRoute(r)).Observe(time.Since(start).Seconds())
+	})
+}
+
+// метод приходит от клиента, поэтому нестандартные методы сводятся к одной метке,
+// иначе число временных рядов ничем не ограничено
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	default:
+		return "OTHER"
+	}
+}
+
+// @Summary Prometheus metrics
+// @Description Request counters, latencies, database pool and runtime metrics in Prometheus text format. Requires songs:admin unless served on -metrics-addr
+// @Tags monitoring
+// @Produce plain
+// @Security BearerAuth
+// @Success 200 {string} string "Metrics"
+// @Failure 401 {string} string "Authentication required"
+// @Failure 403 {string} string "Not permitted"
+// @Router /metrics [get]
+func (app *application) metricsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	err := app.metrics.registry.WritePrometheus(w)
+	if err != nil {
+		app.logError(r, err)
+	}
+}
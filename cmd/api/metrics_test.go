@@ -0,0 +1,26 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestMethodLabel(t *testing.T) {
+	tests := []struct {
+		method string
+		want   string
+	}{
+		{http.MethodGet, "GET"},
+		{http.MethodPost, "POST"},
+		{http.MethodOptions, "OPTIONS"},
+		{"get", "OTHER"},
+		{"PROPFIND", "OTHER"},
+		{"X-RANDOM-1234", "OTHER"},
+	}
+
+	for _, tt := range tests {
+		if got := methodLabel(tt.method); got != tt.want {
+			t.Errorf("methodLabel(%q) = %q, want %q", tt.method, got, tt.want)
+		}
+	}
+}
@@ -1,13 +1,19 @@
 package main
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"net/http"
-	"sync"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
-	"github.com/tomasen/realip"
-	"golang.org/x/time/rate"
+	"github.com/Segren/testTask/internal/data"
+	"github.com/Segren/testTask/internal/tracing"
+	"github.com/Segren/testTask/internal/validator"
 )
 
 func (app *application) recoverPanic(next http.Handler) http.Handler {
@@ -23,59 +29,387 @@ func (app *application) recoverPanic(next http.Handler) http.Handler {
 	})
 }
 
-func (app *application) rateLimit(next http.Handler) http.Handler {
-	type client struct {
-		limiter  *rate.Limiter
-		lastSeen time.Time
-	}
+// идентификатор запроса из заголовка X-Request-ID или новый, если заголовка нет или он некорректен.
+// Возвращается клиенту в том же заголовке
+func (app *application) requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+
+		w.Header().Set("X-Request-ID", id)
+
+		r = app.contextSetRequestID(r, id)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// корневой спан запроса. Продолжает трассировку из заголовка traceparent, если он есть
+func (app *application) traceRequest(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := app.tracer.Start(r.Context(), r.Method, tracing.Extract(r.Header))
+		defer span.End()
+
+		span.SetAttribute("http.method", r.Method)
+		span.SetAttribute("http.url", r.URL.String())
+		span.SetAttribute("request_id", app.contextGetRequestID(r))
+		span.SetAttribute("client_ip", app.contextGetClientIP(r))
+
+		rw := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		r = app.contextSetRoute(r.WithContext(ctx))
+
+		next.ServeHTTP(rw, r)
+
+		//имя спана по шаблону маршрута, чтобы спаны одного эндпоинта группировались
+		route := app.contextGetRoute(r)
+		span.SetName(r.Method + " " + route)
+		span.SetAttribute("http.route", route)
+		span.SetAttribute("http.status_code", strconv.Itoa(rw.status))
+
+		if rw.status >= http.StatusInternalServerError {
+			span.RecordError(fmt.Errorf("status %d", rw.status))
+		}
+	})
+}
+
+// строка запроса в журнале после его обработки
+func (app *application) logRequest(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !app.config.accessLog {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		start := time.Now()
+
+		rw := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		r = app.contextSetRoute(r)
+		//пользователя определит authenticate, который вызывается позже
+		r = app.contextSetUserIDHolder(r)
+
+		next.ServeHTTP(rw, r)
+
+		app.requestLogger(r).PrintInfo("request completed", map[string]string{
+			"request_method": r.Method,
+			"request_url":    r.URL.String(),
+			"route":          app.contextGetRoute(r),
+			"status":         strconv.Itoa(rw.status),
+			"bytes":          strconv.Itoa(rw.bytes),
+			"duration":       time.Since(start).String(),
+			"client_ip":      app.contextGetClientIP(r),
+		})
+	})
+}
+
+// определяет адрес клиента с учетом доверенных прокси и кладет его в контекст
+func (app *application) resolveClientIP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = app.contextSetClientIP(r, app.config.trustedProxies.clientIP(r))
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// разрешает запросы из браузера с доверенных источников и отвечает на preflight запросы
+func (app *application) enableCORS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Origin")
+		w.Header().Add("Vary", "Access-Control-Request-Method")
 
-	var (
-		mu      sync.Mutex
-		clients = make(map[string]*client)
-	)
+		origin := r.Header.Get("Origin")
 
-	//горутина удалящая старые данные из карты клиентов каждую минуту
-	go func() {
-		for {
-			time.Sleep(time.Minute)
+		cors := app.liveConfig().cors
 
-			mu.Lock()
+		if origin != "" && trustedOrigin(cors.trustedOrigins, origin) {
+			//credentials разрешаются только для явно перечисленных источников (проверяется в validate)
+			if slices.Contains(cors.trustedOrigins, "*") {
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+			} else {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
 
-			for ip, client := range clients {
-				if time.Since(client.lastSeen) > 3*time.Minute {
-					delete(clients, ip)
+				if cors.allowCredentials {
+					w.Header().Set("Access-Control-Allow-Credentials", "true")
 				}
 			}
 
-			mu.Unlock()
+			w.Header().Set("Access-Control-Expose-Headers", "Location, Content-Language, RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, Retry-After")
+
+			//preflight запрос
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				w.Header().Set("Access-Control-Allow-Methods", "OPTIONS, GET, POST, PUT, PATCH, DELETE")
+				w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, X-API-Key, Accept-Language")
+				w.Header().Set("Access-Control-Max-Age", "600")
+
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
 		}
-	}()
 
+		next.ServeHTTP(w, r)
+	})
+}
+
+// источник есть в -cors-trusted-origins или разрешены все источники
+func trustedOrigin(trustedOrigins []string, origin string) bool {
+	for _, trusted := range trustedOrigins {
+		if trusted == "*" || strings.EqualFold(origin, trusted) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// общий лимит запросов. Клиент определяется по api-ключу, пользователю или ip адресу,
+// чтение и изменение данных ограничиваются раздельно
+func (app *application) rateLimit(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if app.config.limiter.enabled {
-			//получаем настоящий ip адрес клиента из запроса
-			ip := realip.FromRequest(r)
+		cfg := app.liveConfig().limiter
 
-			mu.Lock()
+		if cfg.enabled {
+			key, limit := app.rateLimitClient(r)
 
-			if _, found := clients[ip]; !found {
-				//2 запроса в секунду в среднем, 4 за раз (по дефолту)
-				clients[ip] = &client{
-					limiter: rate.NewLimiter(rate.Limit(app.config.limiter.rps), app.config.limiter.burst),
-				}
+			if isWriteRequest(r) {
+				key += ":write"
+				limit = limit.Scale(cfg.writeRatio)
+			} else {
+				key += ":read"
+			}
+
+			if !app.allowRequest(w, r, key, limit) {
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// отдельный строгий лимит по ip для входа, регистрации и активации поверх общего
+func (app *application) authRateLimit(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cfg := app.liveConfig().limiter
+
+		if cfg.enabled {
+			if !app.allowRequest(w, r, "auth:"+app.contextGetClientIP(r), cfg.tiers[tierAuth]) {
+				return
 			}
+		}
 
-			clients[ip].lastSeen = time.Now()
+		next.ServeHTTP(w, r)
+	}
+}
+
+// кладет в контекст пользователя по токену из заголовка Authorization: Bearer <token>
+// или по api-ключу из заголовка X-API-Key. Без заголовков в контексте оказывается data.AnonymousUser
+func (app *application) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Authorization")
+		w.Header().Add("Vary", "X-API-Key")
+
+		apiKey := r.Header.Get("X-API-Key")
+
+		//подбор токенов и ключей ограничивается по ip до обращения к бд
+		if apiKey != "" || r.Header.Get("Authorization") != "" {
+			if !app.allowAuthAttempt(w, r) {
+				return
+			}
+		}
 
-			if !clients[ip].limiter.Allow() {
-				mu.Unlock()
-				app.rateLimitExceededResponse(w, r)
+		if apiKey != "" {
+			if r.Header.Get("Authorization") != "" {
+				app.badRequestResponse(w, r, errors.New("use either the Authorization or the X-API-Key header, not both"))
 				return
 			}
 
-			mu.Unlock()
+			app.authenticateAPIKey(w, r, apiKey, next)
+			return
+		}
+
+		token, ok := bearerToken(r)
+		if !ok {
+			if r.Header.Get("Authorization") != "" {
+				app.recordAuthFailure(r)
+				app.invalidAuthenticationTokenResponse(w, r)
+				return
+			}
+
+			r = app.contextSetUser(r, data.AnonymousUser)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		v := validator.New()
+
+		if data.ValidateTokenPlaintext(v, token); !v.Valid() {
+			app.recordAuthFailure(r)
+			app.invalidAuthenticationTokenResponse(w, r)
+			return
+		}
+
+		user, err := app.models.Users.GetForToken(data.ScopeAuthentication, token)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				app.recordAuthFailure(r)
+				app.invalidAuthenticationTokenResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		r = app.contextSetUser(r, user)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// аутентификация по api-ключу. Время использования ключа обновляется не чаще раза в минуту
+func (app *application) authenticateAPIKey(w http.ResponseWriter, r *http.Request, plaintext string, next http.Handler) {
+	key, user, err := app.models.APIKeys.GetForKey(plaintext)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.recordAuthFailure(r)
+			app.invalidAPIKeyResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.APIKeys.TouchLastUsed(key.ID)
+	if err != nil {
+		app.logError(r, err)
+	}
+
+	r = app.contextSetUser(r, user)
+	r = app.contextSetAPIKey(r, key)
+
+	next.ServeHTTP(w, r)
+}
+
+func (app *application) requireAuthenticatedUser(next http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := app.contextGetUser(r)
+
+		if user.IsAnonymous() {
+			app.authenticationRequiredResponse(w, r)
+			return
 		}
 
 		next.ServeHTTP(w, r)
 	})
 }
+
+func (app *application) requireActivatedUser(next http.HandlerFunc) http.HandlerFunc {
+	fn := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := app.contextGetUser(r)
+
+		if !user.Activated {
+			app.inactiveAccountResponse(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+
+	return app.requireAuthenticatedUser(fn)
+}
+
+// токен из заголовка Authorization: Bearer <token>
+func bearerToken(r *http.Request) (string, bool) {
+	authorizationHeader := r.Header.Get("Authorization")
+	if authorizationHeader == "" {
+		return "", false
+	}
+
+	headerParts := strings.Split(authorizationHeader, " ")
+	if len(headerParts) != 2 || headerParts[0] != "Bearer" {
+		return "", false
+	}
+
+	return headerParts[1], true
+}
+
+// пропускает только активированных пользователей с разрешением code
+func (app *application) requirePermission(code string, next http.HandlerFunc) http.HandlerFunc {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		user := app.contextGetUser(r)
+
+		permissions, err := app.models.Permissions.GetAllForUser(user.ID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		if !permissions.Include(code) {
+			app.notPermittedResponse(w, r)
+			return
+		}
+
+		//api-ключ дает только разрешения из своих scopes
+		if key := app.contextGetAPIKey(r); key != nil && !key.Scopes.Include(code) {
+			app.notPermittedResponse(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+
+	return app.requireActivatedUser(fn)
+}
+
+// пропускает только пользователей, вошедших по токену: api-ключом нельзя управлять ключами
+func (app *application) requireUserToken(next http.HandlerFunc) http.HandlerFunc {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		if app.contextGetAPIKey(r) != nil {
+			app.notPermittedResponse(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+
+	return app.requireActivatedUser(fn)
+}
+
+// чтение открыто для всех, если не выключено флагом -songs-public-read
+func (app *application) requireReadAccess(next http.HandlerFunc) http.HandlerFunc {
+	if app.config.songsPublicRead {
+		return next
+	}
+
+	return app.requirePermission(data.PermissionSongsRead, next)
+}
+
+// допустимый идентификатор запроса от клиента: до 128 символов из букв, цифр и -_.:
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', strings.ContainsRune("-_.:", c):
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+
+	//crypto/rand.Read не возвращает ошибок на поддерживаемых платформах
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
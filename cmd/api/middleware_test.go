@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Segren/testTask/internal/data"
+	"github.com/Segren/testTask/internal/jsonlog"
+)
+
+func TestLogRequestIncludesAuthenticatedUser(t *testing.T) {
+	var buf bytes.Buffer
+
+	app := &application{logger: jsonlog.New(&buf, jsonlog.LevelInfo)}
+	app.config.accessLog = true
+
+	//пользователь появляется в контексте позже logRequest, как после authenticate
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = app.contextSetUser(r, &data.User{ID: 42})
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	app.logRequest(next).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/songs", nil))
+
+	var entry struct {
+		Properties map[string]string `json:"properties"`
+	}
+	err := json.Unmarshal(buf.Bytes(), &entry)
+	if err != nil {
+		t.Fatalf("decode log entry %q: %v", buf.String(), err)
+	}
+
+	if got := entry.Properties["user_id"]; got != "42" {
+		t.Errorf("user_id = %q, want %q; entry: %s", got, "42", buf.String())
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/Segren/testTask/internal/jsonlog"
+	"github.com/Segren/testTask/internal/migrate"
+)
+
+const migrateUsage = "usage: api [flags] migrate up | down [steps] | status | goto <version>"
+
+// подкоманда migrate: управление схемой бд встроенными миграциями
+func runMigrate(m *migrate.Migrator, logger *jsonlog.Logger, out io.Writer, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	ctx := context.Background()
+
+	var err error
+
+	switch args[0] {
+	case "up":
+		if len(args) != 1 {
+			return errors.New(migrateUsage)
+		}
+		err = m.Up(ctx)
+	case "down":
+		steps := 1
+		switch len(args) {
+		case 1:
+		case 2:
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+		default:
+			return errors.New(migrateUsage)
+		}
+		err = m.Down(ctx, steps)
+	case "goto":
+		if len(args) != 2 {
+			return errors.New(migrateUsage)
+		}
+		version, parseErr := strconv.ParseInt(args[1], 10, 64)
+		if parseErr != nil || version < 0 {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		err = m.Goto(ctx, version)
+	case "status":
+		if len(args) != 1 {
+			return errors.New(migrateUsage)
+		}
+		return printMigrationStatus(ctx, m, out)
+	default:
+		return errors.New(migrateUsage)
+	}
+
+	switch {
+	case errors.Is(err, migrate.ErrNoChange):
+		logger.PrintInfo("schema is up to date", nil)
+		return nil
+	case err != nil:
+		return err
+	}
+
+	return nil
+}
+
+func printMigrationStatus(ctx context.Context, m *migrate.Migrator, out io.Writer) error {
+	version, dirty, states, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "version: %d (latest %d)", version, m.Latest())
+	if dirty {
+		fmt.Fprint(out, ", dirty")
+	}
+	fmt.Fprintln(out)
+
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	for _, state := range states {
+		status := "pending"
+		if state.Applied {
+			status = "applied"
+		}
+		fmt.Fprintf(tw, "%06d\t%s\t%s\n", state.Migration.Version, state.Migration.Name, status)
+	}
+
+	return tw.Flush()
+}
+
+// применяет миграции при -auto-migrate и проверяет, что схема не отстает от кода
+func (app *application) checkSchema(m *migrate.Migrator) error {
+	ctx := context.Background()
+
+	if app.config.autoMigrate {
+		err := m.Up(ctx)
+		if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+			return fmt.Errorf("auto-migrate: %w", err)
+		}
+	}
+
+	version, dirty, _, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case dirty:
+		return fmt.Errorf("schema version %d: %w", version, migrate.ErrDirty)
+	case version < m.Latest():
+		return fmt.Errorf("schema version %d is behind required %d: run \"api migrate up\" or start with -auto-migrate", version, m.Latest())
+	}
+
+	return nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Segren/testTask/internal/data"
+	"github.com/Segren/testTask/internal/validator"
+)
+
+// @Summary Get user permissions
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {array} string "Permission codes"
+// @Failure 401 {string} string "Authentication required"
+// @Failure 403 {string} string "Not permitted"
+// @Failure 404 {string} string "User not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /admin/users/{id}/permissions [get]
+func (app *application) listUserPermissionsHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := app.readUser(w, r)
+	if !ok {
+		return
+	}
+
+	permissions, err := app.models.Permissions.GetAllForUser(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"permissions": permissions}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// @Summary Grant permissions to a user
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Param permissions body object true "Permission codes to grant"
+// @Success 200 {array} string "Resulting permission codes"
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Authentication required"
+// @Failure 403 {string} string "Not permitted"
+// @Failure 404 {string} string "User not found"
+// @Failure 422 {string} string "Validation error"
+// @Failure 500 {string} string "Internal server error"
+// @Router /admin/users/{id}/permissions [post]
+func (app *application) grantUserPermissionsHandler(w http.ResponseWriter, r *http.Request) {
+	app.changeUserPermissions(w, r, app.models.Permissions.AddForUser)
+}
+
+// @Summary Revoke permissions from a user
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Param permissions body object true "Permission codes to revoke"
+// @Success 200 {array} string "Resulting permission codes"
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Authentication required"
+// @Failure 403 {string} string "Not permitted"
+// @Failure 404 {string} string "User not found"
+// @Failure 422 {string} string "Validation error"
+// @Failure 500 {string} string "Internal server error"
+// @Router /admin/users/{id}/permissions [delete]
+func (app *application) revokeUserPermissionsHandler(w http.ResponseWriter, r *http.Request) {
+	app.changeUserPermissions(w, r, app.models.Permissions.RemoveForUser)
+}
+
+// общая часть выдачи и отзыва разрешений
+func (app *application) changeUserPermissions(w http.ResponseWriter, r *http.Request, change func(int64, ...string) error) {
+	user, ok := app.readUser(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Permissions []string `json:"permissions"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	v.Check(len(input.Permissions) > 0, "permissions", "must contain at least 1 permission")
+	v.Check(validator.Unique(input.Permissions), "permissions", "must not contain duplicate values")
+	for _, code := range input.Permissions {
+		v.Check(validator.PermittedValue(code, data.PermissionCodes...), "permissions", "must contain only known permission codes")
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = change(user.ID, input.Permissions...)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	permissions, err := app.models.Permissions.GetAllForUser(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"permissions": permissions}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// пользователь из параметра маршрута. При ошибке ответ уже отправлен
+func (app *application) readUser(w http.ResponseWriter, r *http.Request) (*data.User, bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	user, err := app.models.Users.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	return user, true
+}
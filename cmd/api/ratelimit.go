@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Segren/testTask/internal/ratelimit"
+)
+
+// уровни лимитов клиентов
+const (
+	tierUser   = "user"
+	tierAPIKey = "api_key"
+	//вход, регистрация и активация, ограничиваются по ip
+	tierAuth = "auth"
+)
+
+// значение флага -limiter-tiers вида "user=40:100,api_key=100:200"
+type limiterTiers map[string]ratelimit.Limit
+
+func (t limiterTiers) String() string {
+	var parts []string
+	for _, name := range []string{tierUser, tierAPIKey, tierAuth} {
+		if tier, ok := t[name]; ok {
+			parts = append(parts, name+"="+tier.String())
+		}
+	}
+
+	return strings.Join(parts, ",")
+}
+
+func (t limiterTiers) Set(value string) error {
+	for _, part := range strings.Split(value, ",") {
+		name, limit, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return fmt.Errorf("invalid tier %q: expected name=rps:burst", part)
+		}
+
+		if name != tierUser && name != tierAPIKey && name != tierAuth {
+			return fmt.Errorf("unknown tier %q", name)
+		}
+
+		rps, burst, ok := strings.Cut(limit, ":")
+		if !ok {
+			return fmt.Errorf("invalid tier %q: expected name=rps:burst", part)
+		}
+
+		tier := ratelimit.Limit{}
+
+		var err error
+
+		tier.Rate, err = strconv.ParseFloat(rps, 64)
+		if err != nil || tier.Rate <= 0 {
+			return fmt.Errorf("invalid rps in tier %q", part)
+		}
+
+		tier.Burst, err = strconv.Atoi(burst)
+		if err != nil || tier.Burst < 1 {
+			return fmt.Errorf("invalid burst in tier %q", part)
+		}
+
+		t[name] = tier
+	}
+
+	return nil
+}
+
+// изменяющие запросы ограничиваются строже чтения
+func isWriteRequest(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	default:
+		return true
+	}
+}
+
+// ключ клиента и его лимит: api-ключ, пользователь или ip адрес для анонимных запросов
+func (app *application) rateLimitClient(r *http.Request) (string, ratelimit.Limit) {
+	cfg := app.liveConfig().limiter
+
+	if key := app.contextGetAPIKey(r); key != nil {
+		return fmt.Sprintf("api_key:%d", key.ID), cfg.tiers[tierAPIKey]
+	}
+
+	if user := app.contextGetUser(r); !user.IsAnonymous() {
+		return fmt.Sprintf("user:%d", user.ID), cfg.tiers[tierUser]
+	}
+
+	return "ip:" + app.contextGetClientIP(r), ratelimit.Limit{Rate: cfg.rps, Burst: cfg.burst}
+}
+
+// проверяет лимит клиента key и выставляет заголовки. При превышении ответ уже отправлен.
+// Если хранилище лимитов недоступно, запрос пропускается
+func (app *application) allowRequest(w http.ResponseWriter, r *http.Request, key string, limit ratelimit.Limit) bool {
+	result, err := app.limiter.Allow(r.Context(), key, limit)
+	if err != nil {
+		app.logError(r, fmt.Errorf("rate limiter: %w", err))
+		return true
+	}
+
+	setRateLimitHeaders(w, result)
+
+	if !result.Allowed {
+		app.rateLimitExceededResponse(w, r)
+		return false
+	}
+
+	return true
+}
+
+// заголовки RateLimit-* (draft-ietf-httpapi-ratelimit-headers) и Retry-After при превышении
+func setRateLimitHeaders(w http.ResponseWriter, result ratelimit.Result) {
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+
+	if !result.Allowed {
+		w.Header().Set("Retry-After", strconv.Itoa(max(1, ceilSeconds(result.RetryAfter))))
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// лимит неудачных попыток аутентификации с одного адреса, уровень auth
+func (app *application) authFailureKey(r *http.Request) string {
+	return "auth_failure:" + app.contextGetClientIP(r)
+}
+
+// проверяет до поиска токена или ключа в бд, что адрес клиента не исчерпал лимит
+// неудачных попыток. Сама попытка лимит не тратит, тратят только неудачные
+func (app *application) allowAuthAttempt(w http.ResponseWriter, r *http.Request) bool {
+	cfg := app.liveConfig().limiter
+	if !cfg.enabled {
+		return true
+	}
+
+	result, err := app.limiter.Peek(r.Context(), app.authFailureKey(r), cfg.tiers[tierAuth])
+	if err != nil {
+		app.logError(r, fmt.Errorf("rate limiter: %w", err))
+		return true
+	}
+
+	if !result.Allowed {
+		setRateLimitHeaders(w, result)
+		app.rateLimitExceededResponse(w, r)
+		return false
+	}
+
+	return true
+}
+
+// списывает неудачную попытку аутентификации с лимита адреса клиента
+func (app *application) recordAuthFailure(r *http.Request) {
+	cfg := app.liveConfig().limiter
+	if !cfg.enabled {
+		return
+	}
+
+	_, err := app.limiter.Allow(r.Context(), app.authFailureKey(r), cfg.tiers[tierAuth])
+	if err != nil {
+		app.logError(r, fmt.Errorf("rate limiter: %w", err))
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Segren/testTask/internal/ratelimit"
+)
+
+func TestFailedAuthenticationIsThrottledByIP(t *testing.T) {
+	app := &application{limiter: ratelimit.NewMemory()}
+	app.config.limiter.enabled = true
+	app.config.limiter.tiers = limiterTiers{tierAuth: {Rate: 0.001, Burst: 3}}
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("request with an invalid token reached the handler")
+	})
+	handler := app.authenticate(next)
+
+	want := []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests}
+
+	for i, status := range want {
+		r := httptest.NewRequest(http.MethodGet, "/songs", nil)
+		r.RemoteAddr = "203.0.113.7:1234"
+		//токен неверной длины отклоняется без обращения к бд
+		r.Header.Set("Authorization", "Bearer forged")
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, r)
+
+		if rr.Code != status {
+			t.Fatalf("request %d: got status %d, want %d", i+1, rr.Code, status)
+		}
+	}
+
+	//другой адрес ограничивается отдельно
+	r := httptest.NewRequest(http.MethodGet, "/songs", nil)
+	r.RemoteAddr = "203.0.113.8:1234"
+	r.Header.Set("Authorization", "Bearer forged")
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, r)
+
+	if rr.Code != http.StatusUnauthorized {
+		t.Fatalf("other client: got status %d, want %d", rr.Code, http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/Segren/testTask/internal/validator"
+)
+
+// @Summary Get similar songs
+// @Description Songs listened to by the same users or sharing the group and language, with explanations
+// @Tags recommendations
+// @Produce json
+// @Param id path int true "Song ID"
+// @Param limit query int false "Maximum number of songs (1-50)"
+// @Success 200 {array} data.SimilarSong "Similar songs"
+// @Failure 404 {string} string "Song not found"
+// @Failure 422 {string} string "Validation error"
+// @Failure 500 {string} string "Internal server error"
+// @Router /songs/{id}/similar [get]
+func (app *application) listSimilarSongsHandler(w http.ResponseWriter, r *http.Request) {
+	songID, ok := app.readExistingSongID(w, r)
+	if !ok {
+		return
+	}
+
+	v := validator.New()
+
+	limit := app.readInt(r.URL.Query(), "limit", 10, v)
+	v.Check(limit >= 1 && limit <= 50, "limit", "must be between 1 and 50")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	songs, err := app.models.Similarity.GetSimilar(songID, limit)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"songs": songs}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// @Summary Get my recommendations
+// @Description Songs similar to the ones the current user listened to, favorited or rated highly. Users without history get popular songs.
+// @Tags recommendations
+// @Produce json
+// @Security BearerAuth
+// @Param limit query int false "Maximum number of songs (1-50)"
+// @Success 200 {array} data.Recommendation "Recommended songs with explanations"
+// @Failure 401 {string} string "Authentication required"
+// @Failure 403 {string} string "Account not activated"
+// @Failure 422 {string} string "Validation error"
+// @Failure 500 {string} string "Internal server error"
+// @Router /me/recommendations [get]
+func (app *application) listRecommendationsHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	limit := app.readInt(r.URL.Query(), "limit", 20, v)
+	v.Check(limit >= 1 && limit <= 50, "limit", "must be between 1 and 50")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	recommendations, err := app.models.Similarity.GetRecommendations(user.ID, limit)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	//истории еще нет, рекомендовать не на основе чего
+	if len(recommendations) == 0 {
+		recommendations, err = app.models.Similarity.GetPopular(user.ID, limit)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"recommendations": recommendations}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// фоновый пересчет похожих песен раз в similarity.interval. Работает до отмены ctx
+func (app *application) startSimilarityRefresher(ctx context.Context) {
+	if !app.config.similarity.enabled {
+		return
+	}
+
+	app.background(func() {
+		ticker := time.NewTicker(app.config.similarity.interval)
+		defer ticker.Stop()
+
+		for {
+			start := time.Now()
+
+			err := app.models.Similarity.Refresh(ctx)
+			switch {
+			case ctx.Err() != nil:
+				return
+			case err != nil:
+				app.logger.PrintError(err, map[string]string{"job": "similarity refresh"})
+			default:
+				app.logger.PrintInfo("song similarity refreshed", map[string]string{
+					"duration": time.Since(start).String(),
+				})
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	})
+}
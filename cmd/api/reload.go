@@ -0,0 +1,102 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// настройки, которые применяются по SIGHUP без перезапуска
+var reloadableFlags = []string{
+	"limiter-enabled",
+	"limiter-rps",
+	"limiter-burst",
+	"limiter-tiers",
+	"limiter-write-ratio",
+	"log-level",
+	"provider-url",
+	"cors-trusted-origins",
+	"cors-allow-credentials",
+}
+
+// последние примененные настройки. Изменяемые по SIGHUP настройки читаются только отсюда
+func (app *application) liveConfig() *config {
+	if cfg := app.live.Load(); cfg != nil {
+		return cfg
+	}
+
+	return &app.config
+}
+
+// перечитывает файл настроек и переменные окружения и применяет изменяемые настройки.
+// Если новые настройки невалидны, работающий сервер не меняется
+func (app *application) reloadConfig() {
+	current := app.liveConfig()
+
+	next, err := loadConfig(current.args, os.Getenv)
+	if err != nil {
+		app.logger.PrintError(fmt.Errorf("config reload rejected: %w", err), nil)
+		return
+	}
+
+	changes := make(map[string]string)
+	var restartRequired []string
+
+	next.flags.VisitAll(func(f *flag.Flag) {
+		old := current.flags.Lookup(f.Name).Value.String()
+		if old == f.Value.String() {
+			return
+		}
+
+		if slices.Contains(reloadableFlags, f.Name) {
+			changes[f.Name] = old + " -> " + f.Value.String()
+		} else {
+			restartRequired = append(restartRequired, f.Name)
+		}
+	})
+
+	//остальные настройки остаются прежними до перезапуска, чтобы live совпадал с тем, что работает
+	for _, name := range restartRequired {
+		err = next.flags.Set(name, current.flags.Lookup(name).Value.String())
+		if err != nil {
+			app.logger.PrintError(fmt.Errorf("config reload rejected: keep %s: %w", name, err), nil)
+			return
+		}
+	}
+
+	//прежние значения вместе с новыми тоже должны быть допустимым сочетанием
+	err = next.validate()
+	if err != nil {
+		app.logger.PrintError(fmt.Errorf("config reload rejected: %w", err), nil)
+		return
+	}
+
+	if len(restartRequired) > 0 {
+		app.logger.PrintWarn("config changes require a restart and were not applied", map[string]string{
+			"options": strings.Join(restartRequired, ", "),
+		})
+	}
+
+	app.live.Store(next)
+
+	//уровень, измененный через /admin/log-level, сохраняется, пока его не поменяли в настройках
+	if _, ok := changes["log-level"]; ok {
+		app.logger.SetLevel(next.log.level)
+	}
+	if _, ok := changes["provider-url"]; ok && app.providerClient != nil {
+		app.providerClient.SetURL(next.provider.url)
+		//ответы прежнего api не должны отдаваться для нового адреса
+		if app.providerCache != nil {
+			app.providerCache.SetScope(next.provider.url)
+		}
+	}
+
+	if len(changes) == 0 {
+		app.logger.PrintInfo("config reloaded without changes", nil)
+		return
+	}
+
+	app.logger.PrintInfo("config reloaded", changes)
+}
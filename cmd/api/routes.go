@@ -1,8 +1,10 @@
 package main
 
 import (
+	"expvar"
 	"net/http"
 
+	"github.com/Segren/testTask/internal/data"
 	"github.com/julienschmidt/httprouter"
 	"github.com/justinas/alice"
 	httpSwagger "github.com/swaggo/http-swagger"
@@ -15,26 +17,114 @@ func (app *application) routes() http.Handler {
 
 	router.MethodNotAllowed = http.HandlerFunc(app.methodNotAllowedResponse)
 
-	router.HandlerFunc(http.MethodGet, "/healthcheck", app.healthcheckHandler)
+	//регистрирует маршрут и запоминает его шаблон для метрик
+	handle := func(method, pattern string, handler http.HandlerFunc) {
+		router.HandlerFunc(method, pattern, app.withRoute(pattern, handler))
+	}
+
+	//healthcheck оставлен для старых клиентов, это то же самое, что livez
+	handle(http.MethodGet, "/healthcheck", app.livenessHandler)
+	handle(http.MethodGet, "/livez", app.livenessHandler)
+	handle(http.MethodGet, "/readyz", app.readinessHandler)
 
 	//получение списка песен с фильтрацией и пагинацией
-	router.HandlerFunc(http.MethodGet, "/songs", app.listSongsHandler)
+	handle(http.MethodGet, "/songs", app.requireReadAccess(app.listSongsHandler))
 
 	//получение текста песни с пагинацией по куплетам
-	router.HandlerFunc(http.MethodGet, "/songs/:id/lyrics", app.getSongLyricsHandler)
+	handle(http.MethodGet, "/songs/:id/lyrics", app.requireReadAccess(app.getSongLyricsHandler))
 	//удаление песни
-	router.HandlerFunc(http.MethodDelete, "/songs/:id", app.deleteSongHandler)
+	handle(http.MethodDelete, "/songs/:id", app.requirePermission(data.PermissionSongsAdmin, app.deleteSongHandler))
 	//изменение данных песни
-	router.HandlerFunc(http.MethodPut, "/songs/:id", app.updateSongHandler)
+	handle(http.MethodPut, "/songs/:id", app.requirePermission(data.PermissionSongsWrite, app.updateSongHandler))
 	//добавление новой песни
-	router.HandlerFunc(http.MethodPost, "/songs", app.createSongHandler)
+	handle(http.MethodPost, "/songs", app.requirePermission(data.PermissionSongsWrite, app.createSongHandler))
+
+	//похожие песни
+	handle(http.MethodGet, "/songs/:id/similar", app.requireReadAccess(app.listSimilarSongsHandler))
+
+	//внешние ссылки песни
+	handle(http.MethodGet, "/songs/:id/links", app.requireReadAccess(app.listSongLinksHandler))
+	handle(http.MethodPost, "/songs/:id/links", app.requirePermission(data.PermissionSongsWrite, app.createSongLinkHandler))
+	handle(http.MethodGet, "/songs/:id/links/:link_id", app.requireReadAccess(app.showSongLinkHandler))
+	handle(http.MethodPatch, "/songs/:id/links/:link_id", app.requirePermission(data.PermissionSongsWrite, app.updateSongLinkHandler))
+	handle(http.MethodDelete, "/songs/:id/links/:link_id", app.requirePermission(data.PermissionSongsWrite, app.deleteSongLinkHandler))
+
+	//переводы названия и текста песни
+	handle(http.MethodGet, "/songs/:id/translations", app.requireReadAccess(app.listSongTranslationsHandler))
+	handle(http.MethodGet, "/songs/:id/translations/:lang", app.requireReadAccess(app.showSongTranslationHandler))
+	handle(http.MethodPut, "/songs/:id/translations/:lang", app.requirePermission(data.PermissionSongsWrite, app.putSongTranslationHandler))
+	handle(http.MethodDelete, "/songs/:id/translations/:lang", app.requirePermission(data.PermissionSongsWrite, app.deleteSongTranslationHandler))
+
+	//регистрация и активация пользователей
+	handle(http.MethodPost, "/users", app.authRateLimit(app.registerUserHandler))
+	handle(http.MethodPut, "/users/activated", app.authRateLimit(app.activateUserHandler))
+
+	//выдача и отзыв токенов аутентификации
+	handle(http.MethodPost, "/tokens/authentication", app.authRateLimit(app.createAuthenticationTokenHandler))
+	handle(http.MethodDelete, "/tokens/authentication", app.requireAuthenticatedUser(app.deleteAuthenticationTokenHandler))
+
+	//избранное, оценки и история прослушиваний текущего пользователя
+	handle(http.MethodGet, "/me/favorites", app.requireActivatedUser(app.listFavoritesHandler))
+	handle(http.MethodPut, "/me/favorites/:id", app.requireActivatedUser(app.addFavoriteHandler))
+	handle(http.MethodDelete, "/me/favorites/:id", app.requireActivatedUser(app.removeFavoriteHandler))
+	handle(http.MethodGet, "/me/ratings", app.requireActivatedUser(app.listRatingsHandler))
+	handle(http.MethodPut, "/me/ratings/:id", app.requireActivatedUser(app.rateSongHandler))
+	handle(http.MethodDelete, "/me/ratings/:id", app.requireActivatedUser(app.deleteRatingHandler))
+	handle(http.MethodGet, "/me/plays", app.requireActivatedUser(app.listPlaysHandler))
+	handle(http.MethodPost, "/me/plays", app.requireActivatedUser(app.createPlayHandler))
+	handle(http.MethodGet, "/me/recommendations", app.requireActivatedUser(app.listRecommendationsHandler))
+
+	//api-ключи для сервисов, управляются только по токену пользователя
+	handle(http.MethodGet, "/api-keys", app.requireUserToken(app.listAPIKeysHandler))
+	handle(http.MethodPost, "/api-keys", app.requireUserToken(app.createAPIKeyHandler))
+	handle(http.MethodGet, "/api-keys/:id", app.requireUserToken(app.showAPIKeyHandler))
+	handle(http.MethodDelete, "/api-keys/:id", app.requireUserToken(app.revokeAPIKeyHandler))
+	handle(http.MethodPost, "/api-keys/:id/rotate", app.requireUserToken(app.rotateAPIKeyHandler))
 
-	router.HandlerFunc(http.MethodGet, "/swagger/*any", httpSwagger.WrapHandler)
+	//управление разрешениями пользователей
+	handle(http.MethodGet, "/admin/users/:id/permissions", app.requirePermission(data.PermissionSongsAdmin, app.listUserPermissionsHandler))
+	handle(http.MethodPost, "/admin/users/:id/permissions", app.requirePermission(data.PermissionSongsAdmin, app.grantUserPermissionsHandler))
+	handle(http.MethodDelete, "/admin/users/:id/permissions", app.requirePermission(data.PermissionSongsAdmin, app.revokeUserPermissionsHandler))
+
+	handle(http.MethodGet, "/admin/log-level", app.requirePermission(data.PermissionSongsAdmin, app.showLogLevelHandler))
+	handle(http.MethodPut, "/admin/log-level", app.requirePermission(data.PermissionSongsAdmin, app.updateLogLevelHandler))
+
+	handle(http.MethodGet, "/swagger/*any", httpSwagger.WrapHandler)
+
+	//метрики приложения, в т.ч. статистика кеша внешнего api. В cmdline есть строка подключения к бд,
+	//поэтому на порту api они только для администраторов, а с -metrics-addr - на отдельном адресе
+	if app.config.metricsAddr == "" {
+		handle(http.MethodGet, "/debug/vars", app.requirePermission(data.PermissionSongsAdmin, expvar.Handler().ServeHTTP))
+		if app.config.metricsPath != "" {
+			handle(http.MethodGet, app.config.metricsPath, app.requirePermission(data.PermissionSongsAdmin, app.metricsHandler))
+		}
+	}
 
 	standard := alice.New(
-		app.recoverPanic, //обработчик для восстановления после паники
-		app.rateLimit,    //обработчик для ограничения частоты запросов
+		app.recoverPanic,    //восстановление после паники в самих middleware, поэтому первым
+		app.recordMetrics,   //счетчики запросов и время обработки
+		app.requestID,       //идентификатор запроса из X-Request-ID или новый
+		app.resolveClientIP, //адрес клиента с учетом доверенных прокси
+		app.traceRequest,    //спан запроса, продолжающий трассировку из traceparent
+		app.logRequest,      //журнал запросов
+		app.recoverPanic,    //паника в обработчике: ответ 500 еще попадает в журнал и метрики
+		app.enableCORS,      //заголовки CORS и ответ на preflight запросы
+		app.authenticate,    //пользователь по токену из заголовка Authorization или по X-API-Key
+		app.rateLimit,       //ограничение частоты запросов, после authenticate чтобы учитывать пользователя
 	)
 
 	return standard.Then(router)
 }
+
+// маршруты отдельного адреса -metrics-addr. Аутентификации нет, поэтому адрес
+// должен быть доступен только сборщику метрик
+func (app *application) metricsRoutes() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.Handle("GET /debug/vars", expvar.Handler())
+	if app.config.metricsPath != "" {
+		mux.HandleFunc("GET "+app.config.metricsPath, app.metricsHandler)
+	}
+
+	return mux
+}
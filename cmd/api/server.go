@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -20,8 +21,47 @@ func (app *application) serve() error {
 		WriteTimeout: 30 * time.Second,
 	}
 
+	//метрики на отдельном адресе, закрываются вместе с основным сервером
+	var metricsSrv *http.Server
+	if app.config.metricsAddr != "" {
+		metricsSrv = &http.Server{
+			Addr:         app.config.metricsAddr,
+			Handler:      app.metricsRoutes(),
+			IdleTimeout:  time.Minute,
+			ReadTimeout:  10 * time.Second,
+			WriteTimeout: 30 * time.Second,
+		}
+	}
+
 	shutdownError := make(chan error)
 
+	//контекст фоновых задач, отменяется при остановке сервера
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	app.startLinkChecker(ctx)
+	app.startSimilarityRefresher(ctx)
+
+	//SIGHUP применяет измененные настройки без перезапуска
+	hup := app.hup
+	if hup == nil {
+		hup = make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+	}
+
+	go func() {
+		defer signal.Stop(hup)
+
+		for {
+			select {
+			case <-hup:
+				app.reloadConfig()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
 	//graceful shutdown
 	go func() {
 		quit := make(chan os.Signal, 1)
@@ -33,10 +73,19 @@ func (app *application) serve() error {
 			"signal": s.String(),
 		})
 
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
+		//новые подключения еще принимаются, но балансировщик видит, что сервер не готов
+		app.shuttingDown.Store(true)
+		time.Sleep(app.config.shutdownDelay)
+
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer shutdownCancel()
+
+		if metricsSrv != nil {
+			//метрики не должны мешать остановке api
+			_ = metricsSrv.Shutdown(shutdownCtx)
+		}
 
-		err := srv.Shutdown(ctx)
+		err := srv.Shutdown(shutdownCtx)
 		if err != nil {
 			shutdownError <- err
 		}
@@ -45,6 +94,7 @@ func (app *application) serve() error {
 			"addr": srv.Addr,
 		})
 
+		cancel()
 		app.wg.Wait()
 		shutdownError <- nil
 	}()
@@ -54,6 +104,25 @@ func (app *application) serve() error {
 		"env":  app.config.env,
 	})
 
+	if metricsSrv != nil {
+		//адрес проверяется до запуска api, чтобы ошибка в настройках сразу останавливала сервер
+		ln, err := net.Listen("tcp", metricsSrv.Addr)
+		if err != nil {
+			return fmt.Errorf("metrics listener: %w", err)
+		}
+
+		app.logger.PrintInfo("starting metrics server", map[string]string{
+			"addr": metricsSrv.Addr,
+		})
+
+		go func() {
+			err := metricsSrv.Serve(ln)
+			if !errors.Is(err, http.ErrServerClosed) {
+				app.logger.PrintError(fmt.Errorf("metrics server: %w", err), nil)
+			}
+		}()
+	}
+
 	err := srv.ListenAndServe()
 	if !errors.Is(err, http.ErrServerClosed) {
 		return err
@@ -1,13 +1,15 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
 	"github.com/Segren/testTask/internal/data"
+	"github.com/Segren/testTask/internal/langdetect"
+	"github.com/Segren/testTask/internal/provider"
+	"github.com/Segren/testTask/internal/tracing"
 	"github.com/Segren/testTask/internal/validator"
 	"net/http"
-	"net/url"
 )
 
 // @Summary Get list of songs
@@ -17,16 +19,21 @@ import (
 // @Produce json
 // @Param group query string false "Filter by group"
 // @Param name query string false "Filter by song name"
+// @Param release_from query string false "Released on or after date (YYYY, YYYY-MM or YYYY-MM-DD)"
+// @Param release_to query string false "Released on or before date (YYYY, YYYY-MM or YYYY-MM-DD)"
 // @Param page query int false "Page number"
 // @Param page_size query int false "Number of items per page"
-// @Param sort query string false "Sort order (e.g., 'id', '-id', 'name', '-name')"
+// @Param sort query string false "Sort order (e.g., 'id', '-id', 'name', '-name', 'releaseDate', '-releaseDate', '-popularity', '-rating')"
 // @Success 200 {object} data.SongsResponse "List of songs with metadata"
+// @Failure 422 {string} string "Validation error"
 // @Failure 500 {string} string "Internal server error"
 // @Router /songs [get]
 func (app *application) listSongsHandler(w http.ResponseWriter, r *http.Request) {
 	var input struct {
-		Name  string
-		Group string
+		Name        string
+		Group       string
+		ReleaseFrom data.Date
+		ReleaseTo   data.Date
 		data.Filters
 	}
 
@@ -37,13 +44,21 @@ func (app *application) listSongsHandler(w http.ResponseWriter, r *http.Request)
 	input.Group = app.readString(qs, "group", "")
 	input.Name = app.readString(qs, "name", "")
 
+	input.ReleaseFrom = app.readDate(qs, "release_from", v)
+	input.ReleaseTo = app.readDate(qs, "release_to", v)
+
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
 	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
 	input.Filters.Sort = app.readString(qs, "sort", "id")
 
-	input.Filters.SortSafelist = []string{"id", "group", "name", "-id", "-group", "-name"}
+	input.Filters.SortSafelist = data.SongSortSafelist()
 
-	songs, metadata, err := app.models.Songs.GetAll(input.Name, input.Group, input.Filters)
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	songs, metadata, err := app.models.Songs.GetAll(r.Context(), input.Name, input.Group, input.ReleaseFrom, input.ReleaseTo, input.Filters)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -65,6 +80,10 @@ func (app *application) listSongsHandler(w http.ResponseWriter, r *http.Request)
 // @Header 201 {string} Location "/songs/{id}" "URL of the created song"
 // @Failure 422 {string} string
 // @Failure 500 {string} string "the server encountered a problem and could not process your request"
+// @Failure 502 {string} string "the info provider returned invalid song details"
+// @Security BearerAuth
+// @Failure 401 {string} string "Authentication required"
+// @Failure 403 {string} string "Account not activated or not permitted"
 // @Router /songs [post]
 func (app *application) createSongHandler(w http.ResponseWriter, r *http.Request) {
 	var input struct {
@@ -78,19 +97,9 @@ func (app *application) createSongHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	// Запрос к внешнему API для получения дополнительных данных.
-	songDetail, err := app.fetchSongDetails(input.Group, input.Song)
-	if err != nil {
-		app.serverErrorResponse(w, r, err)
-		return
-	}
-
 	song := &data.Song{
-		Group:       input.Group,
-		Song:        input.Song,
-		ReleaseDate: songDetail.ReleaseDate,
-		Text:        songDetail.Text,
-		Link:        songDetail.Link,
+		Group: input.Group,
+		Song:  input.Song,
 	}
 
 	//инициализация валидатора
@@ -101,7 +110,30 @@ func (app *application) createSongHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	err = app.models.Songs.Insert(song)
+	// Запрос к внешнему API для получения дополнительных данных.
+	songDetail, err := app.fetchSongDetails(r.Context(), input.Group, input.Song)
+	if err != nil {
+		switch {
+		case errors.Is(err, provider.ErrNotFound):
+			app.failedValidationResponse(w, r, map[string]string{"song": "not found in info provider"})
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	//данные внешнего api проверяются отдельно: их ошибки - это 502, а не ошибка клиента
+	pv := validator.New()
+
+	if data.ValidateSongDetail(pv, song, songDetail); !pv.Valid() {
+		app.badGatewayResponse(w, r, pv.Errors)
+		return
+	}
+
+	song.Language = langdetect.Detect(song.Text)
+
+	//песня и ссылка от внешнего api (первая ссылка песни) сохраняются в одной транзакции
+	err = app.models.Songs.InsertMany(r.Context(), []*data.Song{song}, "info provider")
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -116,36 +148,21 @@ func (app *application) createSongHandler(w http.ResponseWriter, r *http.Request
 	}
 }
 
-func (app *application) fetchSongDetails(group, song string) (*data.Song, error) {
-	//тут должен быть нужный внешний адрес
-	url := fmt.Sprintf("http://localhost:8081/info?group=%s&song=%s", url.QueryEscape(group), url.QueryEscape(song))
-
-	resp, err := http.Get(url)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+// данные о песне из внешнего api (через кеш)
+func (app *application) fetchSongDetails(ctx context.Context, group, song string) (*data.SongDetail, error) {
+	ctx, span := tracing.Start(ctx, "fetchSongDetails")
+	defer span.End()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
-	}
+	span.SetAttribute("song.group", group)
+	span.SetAttribute("song.name", song)
 
-	var songDetail struct {
-		ReleaseDate string `json:"releaseDate"`
-		Text        string `json:"text"`
-		Link        string `json:"link"`
+	detail, err := app.provider.Fetch(ctx, group, song)
+	//отсутствие песни у внешнего api - ответ клиенту, а не сбой
+	if err != nil && !errors.Is(err, provider.ErrNotFound) {
+		span.RecordError(err)
 	}
 
-	err = json.NewDecoder(resp.Body).Decode(&songDetail)
-	if err != nil {
-		return nil, err
-	}
-
-	return &data.Song{
-		ReleaseDate: songDetail.ReleaseDate,
-		Text:        songDetail.Text,
-		Link:        songDetail.Link,
-	}, nil
+	return detail, err
 }
 
 // @Summary Delete a song
@@ -157,6 +174,9 @@ func (app *application) fetchSongDetails(group, song string) (*data.Song, error)
 // @Success 200 {string} string "Message indicating successful deletion"
 // @Failure 404 {string} string "Song not found"
 // @Failure 500 {string} string "Internal server error"
+// @Security BearerAuth
+// @Failure 401 {string} string "Authentication required"
+// @Failure 403 {string} string "Account not activated or not permitted"
 // @Router /songs/{id} [delete]
 func (app *application) deleteSongHandler(w http.ResponseWriter, r *http.Request) {
 	id, err := app.readIDParam(r)
@@ -165,7 +185,7 @@ func (app *application) deleteSongHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	err = app.models.Songs.Delete(id)
+	err = app.models.Songs.Delete(r.Context(), id)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -195,6 +215,9 @@ func (app *application) deleteSongHandler(w http.ResponseWriter, r *http.Request
 // @Failure 409 {string} string "Edit conflict occurred"
 // @Failure 422 {string} string "Validation error"
 // @Failure 500 {string} string "Internal server error"
+// @Security BearerAuth
+// @Failure 401 {string} string "Authentication required"
+// @Failure 403 {string} string "Account not activated or not permitted"
 // @Router /songs/{id} [put]
 func (app *application) updateSongHandler(w http.ResponseWriter, r *http.Request) {
 	id, err := app.readIDParam(r)
@@ -203,7 +226,7 @@ func (app *application) updateSongHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	song, err := app.models.Songs.Get(id)
+	song, err := app.models.Songs.Get(r.Context(), id)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -239,7 +262,7 @@ func (app *application) updateSongHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	err = app.models.Songs.Update(song)
+	err = app.models.Songs.Update(r.Context(), song)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrEditConflict):
@@ -257,17 +280,21 @@ func (app *application) updateSongHandler(w http.ResponseWriter, r *http.Request
 }
 
 // @Summary Get lyrics of a song
-// @Description Retrieve song lyrics with pagination by verses
+// @Description Retrieve song lyrics with pagination by verses. The language is chosen by the lang parameter or the Accept-Language header; translations are paginated by the same verses as the original.
 // @Tags songs
 // @Accept json
 // @Produce json
 // @Param id path int true "Song ID"
 // @Param page query int false "Page number"
 // @Param size query int false "Number of verses per page"
+// @Param lang query string false "Language code (BCP 47), takes precedence over Accept-Language"
+// @Param side_by_side query bool false "Return original and translated verses in pairs"
+// @Param Accept-Language header string false "Preferred languages"
 // @Success 200 {object} map[string]interface{} "Lyrics with pagination"
 // @Failure 400 {string} string "Bad request or invalid parameters"
-// @Failure 404 {string} string "Song not found"
-// @Failure 500 {string} stringg "Internal server error"
+// @Failure 404 {string} string "Song or translation not found"
+// @Failure 422 {string} string "Validation error"
+// @Failure 500 {string} string "Internal server error"
 // @Router /songs/{id}/lyrics [get]
 func (app *application) getSongLyricsHandler(w http.ResponseWriter, r *http.Request) {
 	id, err := app.readIDParam(r)
@@ -276,7 +303,7 @@ func (app *application) getSongLyricsHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	song, err := app.models.Songs.Get(id)
+	song, err := app.models.Songs.Get(r.Context(), id)
 	if err != nil {
 		if errors.Is(err, data.ErrRecordNotFound) {
 			app.notFoundResponse(w, r)
@@ -288,19 +315,88 @@ func (app *application) getSongLyricsHandler(w http.ResponseWriter, r *http.Requ
 
 	v := validator.New()
 
-	if data.ValidateSong(v, song); !v.Valid() {
+	qs := r.URL.Query()
+
+	page := app.readInt(qs, "page", 1, v)
+	size := app.readInt(qs, "size", 1, v)
+	sideBySide := app.readBool(qs, "side_by_side", v)
+
+	languages, err := app.models.Translations.GetLanguages(r.Context(), id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	index, found := app.negotiateLanguage(r, v, song.Language, languages)
+
+	if sideBySide != nil && *sideBySide && found && index == 0 {
+		v.AddError("lang", "must select a translation for side-by-side output")
+	}
+
+	if !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	page := app.readInt(r.URL.Query(), "page", 1, v)
-	size := app.readInt(r.URL.Query(), "size", 1, v)
+	if !found {
+		app.notFoundResponse(w, r)
+		return
+	}
 
-	lyrics, err := app.models.Songs.GetLyricsByID(song, id, page, size)
+	lyrics, err := app.models.Songs.GetLyricsByID(r.Context(), song, id, page, size)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	app.writeJSON(w, http.StatusOK, envelope{"lyrics": lyrics}, nil)
+	if index == 0 {
+		headers := make(http.Header)
+		if song.Language != "" && song.Language != langdetect.Undetermined {
+			headers.Set("Content-Language", song.Language)
+		}
+		headers.Set("Vary", "Accept-Language")
+
+		app.writeJSON(w, http.StatusOK, envelope{"lyrics": lyrics, "language": song.Language, "title": song.Song}, headers)
+		return
+	}
+
+	translation, err := app.models.Translations.Get(r.Context(), id, languages[index-1])
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	translated := data.PaginateVerses(translation.Text, page, size)
+
+	headers := make(http.Header)
+	headers.Set("Content-Language", translation.Language)
+	headers.Set("Vary", "Accept-Language")
+
+	env := envelope{"language": translation.Language, "title": translation.Title}
+
+	if sideBySide != nil && *sideBySide {
+		//куплеты перевода выравниваются по куплетам оригинала
+		type versePair struct {
+			Original    string `json:"original"`
+			Translation string `json:"translation"`
+		}
+
+		pairs := make([]versePair, max(len(lyrics), len(translated)))
+		for i := range pairs {
+			if i < len(lyrics) {
+				pairs[i].Original = lyrics[i]
+			}
+			if i < len(translated) {
+				pairs[i].Translation = translated[i]
+			}
+		}
+
+		env["original_language"] = song.Language
+		env["original_title"] = song.Song
+		env["lyrics"] = pairs
+	} else {
+		env["lyrics"] = translated
+	}
+
+	app.writeJSON(w, http.StatusOK, env, headers)
 }
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Segren/testTask/internal/data"
+	"github.com/Segren/testTask/internal/validator"
+	"github.com/julienschmidt/httprouter"
+	"golang.org/x/text/language"
+)
+
+// @Summary Get song translations
+// @Tags translations
+// @Produce json
+// @Param id path int true "Song ID"
+// @Success 200 {array} data.Translation "Translations of the song"
+// @Failure 404 {string} string "Song not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /songs/{id}/translations [get]
+func (app *application) listSongTranslationsHandler(w http.ResponseWriter, r *http.Request) {
+	songID, ok := app.readExistingSongID(w, r)
+	if !ok {
+		return
+	}
+
+	translations, err := app.models.Translations.GetAllForSong(r.Context(), songID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"translations": translations}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// @Summary Add or replace a song translation
+// @Description Store the translated title and lyrics of a song. Verses must be separated by a blank line to align with the original.
+// @Tags translations
+// @Accept json
+// @Produce json
+// @Param id path int true "Song ID"
+// @Param lang path string true "Language code (BCP 47)"
+// @Param translation body data.Translation true "Translated title and lyrics"
+// @Success 200 {object} data.Translation "Stored translation"
+// @Failure 400 {string} string "Bad request"
+// @Failure 404 {string} string "Song not found"
+// @Failure 422 {string} string "Validation error"
+// @Failure 500 {string} string "Internal server error"
+// @Security BearerAuth
+// @Failure 401 {string} string "Authentication required"
+// @Failure 403 {string} string "Account not activated or not permitted"
+// @Router /songs/{id}/translations/{lang} [put]
+func (app *application) putSongTranslationHandler(w http.ResponseWriter, r *http.Request) {
+	songID, ok := app.readExistingSongID(w, r)
+	if !ok {
+		return
+	}
+
+	v := validator.New()
+
+	lang := app.readLanguageParam(r, v)
+
+	var input struct {
+		Title string `json:"title"`
+		Text  string `json:"text"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	translation := &data.Translation{
+		SongID:   songID,
+		Language: lang,
+		Title:    input.Title,
+		Text:     input.Text,
+	}
+
+	if data.ValidateTranslation(v, translation); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Translations.Upsert(r.Context(), translation)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"translation": translation}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// @Summary Get a song translation
+// @Tags translations
+// @Produce json
+// @Param id path int true "Song ID"
+// @Param lang path string true "Language code (BCP 47)"
+// @Success 200 {object} data.Translation "Translation"
+// @Failure 404 {string} string "Translation not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /songs/{id}/translations/{lang} [get]
+func (app *application) showSongTranslationHandler(w http.ResponseWriter, r *http.Request) {
+	songID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	lang, err := data.NormalizeLanguage(httprouter.ParamsFromContext(r.Context()).ByName("lang"))
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	translation, err := app.models.Translations.Get(r.Context(), songID, lang)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"translation": translation}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// @Summary Delete a song translation
+// @Tags translations
+// @Produce json
+// @Param id path int true "Song ID"
+// @Param lang path string true "Language code (BCP 47)"
+// @Success 200 {string} string "Message indicating successful deletion"
+// @Failure 404 {string} string "Translation not found"
+// @Failure 500 {string} string "Internal server error"
+// @Security BearerAuth
+// @Failure 401 {string} string "Authentication required"
+// @Failure 403 {string} string "Account not activated or not permitted"
+// @Router /songs/{id}/translations/{lang} [delete]
+func (app *application) deleteSongTranslationHandler(w http.ResponseWriter, r *http.Request) {
+	songID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	lang, err := data.NormalizeLanguage(httprouter.ParamsFromContext(r.Context()).ByName("lang"))
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Translations.Delete(r.Context(), songID, lang)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "translation successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// код языка из параметра маршрута в канонической форме
+func (app *application) readLanguageParam(r *http.Request, v *validator.Validator) string {
+	lang, err := data.NormalizeLanguage(httprouter.ParamsFromContext(r.Context()).ByName("lang"))
+	if err != nil {
+		v.AddError("language", "must be a valid BCP 47 language code")
+		return ""
+	}
+
+	return lang
+}
+
+// выбирает язык текста среди оригинала (индекс 0) и переводов (индекс i+1).
+// Параметр lang имеет приоритет над Accept-Language; если язык из lang недоступен, ok == false.
+// Для Accept-Language без подходящего перевода возвращается оригинал
+func (app *application) negotiateLanguage(r *http.Request, v *validator.Validator, original string, translations []string) (index int, ok bool) {
+	if original == "" {
+		original = "und"
+	}
+
+	supported := []language.Tag{language.Make(original)}
+	for _, lang := range translations {
+		supported = append(supported, language.Make(lang))
+	}
+
+	matcher := language.NewMatcher(supported)
+
+	if lang := r.URL.Query().Get("lang"); lang != "" {
+		tag, err := language.Parse(lang)
+		if err != nil {
+			v.AddError("lang", "must be a valid BCP 47 language code")
+			return 0, false
+		}
+
+		_, index, confidence := matcher.Match(tag)
+		return index, confidence != language.No
+	}
+
+	tags, _, err := language.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+	if err != nil || len(tags) == 0 {
+		return 0, true
+	}
+
+	_, index, confidence := matcher.Match(tags...)
+	if confidence == language.No {
+		return 0, true
+	}
+
+	return index, true
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/Segren/testTask/internal/validator"
+)
+
+func TestNegotiateLanguage(t *testing.T) {
+	app := &application{}
+
+	tests := []struct {
+		name           string
+		original       string
+		translations   []string
+		lang           string
+		acceptLanguage string
+		wantIndex      int
+		wantOK         bool
+		wantInvalid    bool
+	}{
+		{
+			name:         "no preference returns the original",
+			original:     "ru",
+			translations: []string{"de", "en"},
+			wantIndex:    0,
+			wantOK:       true,
+		},
+		{
+			name:         "lang selects a translation",
+			original:     "ru",
+			translations: []string{"de", "en"},
+			lang:         "en",
+			wantIndex:    2,
+			wantOK:       true,
+		},
+		{
+			name:         "lang selects the original",
+			original:     "ru",
+			translations: []string{"de", "en"},
+			lang:         "ru",
+			wantIndex:    0,
+			wantOK:       true,
+		},
+		{
+			name:         "lang with region matches the base language",
+			original:     "ru",
+			translations: []string{"en"},
+			lang:         "en-GB",
+			wantIndex:    1,
+			wantOK:       true,
+		},
+		{
+			name:         "unavailable lang",
+			original:     "ru",
+			translations: []string{"en"},
+			lang:         "ja",
+			wantOK:       false,
+		},
+		{
+			name:         "invalid lang",
+			original:     "ru",
+			translations: []string{"en"},
+			lang:         "not a language",
+			wantOK:       false,
+			wantInvalid:  true,
+		},
+		{
+			name:           "lang takes precedence over Accept-Language",
+			original:       "ru",
+			translations:   []string{"de", "en"},
+			lang:           "de",
+			acceptLanguage: "en",
+			wantIndex:      1,
+			wantOK:         true,
+		},
+		{
+			name:           "highest q-value wins",
+			original:       "ru",
+			translations:   []string{"de", "en"},
+			acceptLanguage: "de;q=0.5, en;q=0.9",
+			wantIndex:      2,
+			wantOK:         true,
+		},
+		{
+			name:           "lower q-value used when the preferred language is missing",
+			original:       "ru",
+			translations:   []string{"de", "en"},
+			acceptLanguage: "fr, de;q=0.7",
+			wantIndex:      1,
+			wantOK:         true,
+		},
+		{
+			name:           "wildcard returns the original",
+			original:       "ru",
+			translations:   []string{"de", "en"},
+			acceptLanguage: "*",
+			wantIndex:      0,
+			wantOK:         true,
+		},
+		{
+			name:           "no matching translation falls back to the original",
+			original:       "ru",
+			translations:   []string{"de"},
+			acceptLanguage: "ja",
+			wantIndex:      0,
+			wantOK:         true,
+		},
+		{
+			name:           "malformed Accept-Language falls back to the original",
+			original:       "ru",
+			translations:   []string{"de"},
+			acceptLanguage: "de;q=abc",
+			wantIndex:      0,
+			wantOK:         true,
+		},
+		{
+			name:           "undetermined original",
+			original:       "",
+			translations:   []string{"en"},
+			acceptLanguage: "en-US",
+			wantIndex:      1,
+			wantOK:         true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := "/songs/1/lyrics"
+			if tt.lang != "" {
+				target += "?lang=" + url.QueryEscape(tt.lang)
+			}
+
+			r := httptest.NewRequest("GET", target, nil)
+			if tt.acceptLanguage != "" {
+				r.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
+
+			v := validator.New()
+
+			index, ok := app.negotiateLanguage(r, v, tt.original, tt.translations)
+			if ok != tt.wantOK {
+				t.Fatalf("got ok %v, want %v", ok, tt.wantOK)
+			}
+			if ok && index != tt.wantIndex {
+				t.Errorf("got index %d, want %d", index, tt.wantIndex)
+			}
+			if _, invalid := v.Errors["lang"]; invalid != tt.wantInvalid {
+				t.Errorf("got lang error %v, want %v", invalid, tt.wantInvalid)
+			}
+		})
+	}
+}
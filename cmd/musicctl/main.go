@@ -0,0 +1,205 @@
+// musicctl - консольная утилита администратора каталога песен. Работает напрямую с бд
+// через модели internal/data и берет строку подключения так же, как api
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	_ "github.com/lib/pq"
+
+	"github.com/Segren/testTask/internal/configfile"
+	"github.com/Segren/testTask/internal/data"
+	"github.com/Segren/testTask/internal/provider"
+)
+
+const usage = `usage: musicctl [flags] <command> [command flags] [args]
+
+commands:
+  list      list songs (-group, -name, -from, -to, -page, -page-size, -sort)
+  search    full text search by song name: search <query>
+  show      show a song: show <id>
+  create    create a song, details are fetched from the info provider unless -fetch=false
+  edit      change song fields: edit [-group] [-song] [-release-date] [-text | -text-file] <id>
+  delete    delete songs: delete <id>...
+  enrich    fetch release date, text and link from the info provider again: enrich <id>...
+  import    create songs from a JSON file (- for stdin): import [-fetch] <file>
+  export    write songs to a JSON file (- for stdout): export [-group] [-o file]
+  stats     catalog statistics
+
+flags:`
+
+// настройки утилиты. Строка подключения и внешнее api задаются теми же флагами,
+// переменными окружения MUSIC_* и файлом настроек, что у api
+type config struct {
+	dsn             string
+	providerURL     string
+	providerTimeout time.Duration
+	format          string
+}
+
+type application struct {
+	config   config
+	models   data.Models
+	provider *provider.Client
+	out      io.Writer
+}
+
+func main() {
+	err := run(os.Args[1:])
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		fmt.Fprintln(os.Stderr, "musicctl:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	var cfg config
+	var configPath string
+
+	fs := flag.NewFlagSet("musicctl", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), usage)
+		fs.PrintDefaults()
+	}
+
+	fs.StringVar(&configPath, "config", "", "Path to the API YAML configuration file (or MUSIC_CONFIG)")
+	fs.StringVar(&cfg.dsn, "db-dsn", "", "PostgreSQL DSN (or MUSIC_DB_DSN)")
+	fs.StringVar(&cfg.providerURL, "provider-url", "http://localhost:8081/info", "Song info provider URL (or MUSIC_PROVIDER_URL)")
+	fs.DurationVar(&cfg.providerTimeout, "provider-timeout", 5*time.Second, "Song info provider request timeout")
+	fs.StringVar(&cfg.format, "format", "table", "Output format (table|json)")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	err = cfg.resolve(fs, configPath)
+	if err != nil {
+		return err
+	}
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("command must be provided")
+	}
+
+	if cfg.format != "table" && cfg.format != "json" {
+		return fmt.Errorf("unknown format %q", cfg.format)
+	}
+
+	if cfg.dsn == "" {
+		return errors.New("database DSN must be set with -db-dsn, MUSIC_DB_DSN or db.dsn in the config file")
+	}
+
+	db, err := openDB(cfg.dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	app := &application{
+		config:   cfg,
+		models:   data.NewModels(db),
+		provider: provider.NewClient(cfg.providerURL, cfg.providerTimeout),
+		out:      os.Stdout,
+	}
+
+	return app.dispatch(context.Background(), fs.Arg(0), fs.Args()[1:])
+}
+
+// значения, не заданные флагами, берутся из переменных окружения, затем из файла настроек
+func (cfg *config) resolve(fs *flag.FlagSet, configPath string) error {
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	if configPath == "" {
+		configPath = os.Getenv("MUSIC_CONFIG")
+	}
+
+	var file map[string]string
+	if configPath != "" {
+		var err error
+		file, err = configfile.Load(configPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, name := range []string{"db-dsn", "provider-url", "provider-timeout"} {
+		if explicit[name] {
+			continue
+		}
+
+		value := os.Getenv(configfile.EnvName(name))
+		if value == "" {
+			value = file[name]
+		}
+		if value == "" {
+			continue
+		}
+
+		err := fs.Set(name, value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+func openDB(dsn string) (*sql.DB, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err = db.PingContext(ctx)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+func (app *application) dispatch(ctx context.Context, command string, args []string) error {
+	switch command {
+	case "list":
+		return app.listCommand(ctx, args)
+	case "search":
+		return app.searchCommand(ctx, args)
+	case "show":
+		return app.showCommand(ctx, args)
+	case "create":
+		return app.createCommand(ctx, args)
+	case "edit":
+		return app.editCommand(ctx, args)
+	case "delete":
+		return app.deleteCommand(ctx, args)
+	case "enrich":
+		return app.enrichCommand(ctx, args)
+	case "import":
+		return app.importCommand(ctx, args)
+	case "export":
+		return app.exportCommand(ctx, args)
+	case "stats":
+		return app.statsCommand(ctx, args)
+	default:
+		return fmt.Errorf("unknown command %q, run musicctl -h for the list of commands", command)
+	}
+}
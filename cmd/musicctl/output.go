@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"unicode/utf8"
+
+	"github.com/Segren/testTask/internal/data"
+)
+
+// максимальная ширина колонки таблицы, длинные значения обрезаются
+const maxCellWidth = 40
+
+type envelope map[string]any
+
+// вывод в json в том же виде, что и ответы api
+func (app *application) writeJSON(env envelope) error {
+	js, err := json.MarshalIndent(env, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(app.out, "%s\n", js)
+	return err
+}
+
+func (app *application) writeTable(header []string, rows [][]string) error {
+	tw := tabwriter.NewWriter(app.out, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			cells[i] = truncate(cell)
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+
+	return tw.Flush()
+}
+
+func (app *application) printSongs(songs []*data.Song, metadata data.Metadata) error {
+	if app.config.format == "json" {
+		return app.writeJSON(envelope{"songs": songs, "metadata": metadata})
+	}
+
+	rows := make([][]string, 0, len(songs))
+	for _, song := range songs {
+		rows = append(rows, []string{
+			fmt.Sprint(song.ID),
+			song.Group,
+			song.Song,
+			song.ReleaseDate.String(),
+			song.Language,
+			fmt.Sprint(song.PlayCount),
+			formatRating(song),
+		})
+	}
+
+	err := app.writeTable([]string{"ID", "GROUP", "NAME", "RELEASED", "LANG", "PLAYS", "RATING"}, rows)
+	if err != nil {
+		return err
+	}
+
+	if metadata.TotalRecords > 0 {
+		_, err = fmt.Fprintf(app.out, "\npage %d of %d, %d songs\n", metadata.CurrentPage, metadata.LastPage, metadata.TotalRecords)
+	}
+
+	return err
+}
+
+func (app *application) printSong(song *data.Song) error {
+	if app.config.format == "json" {
+		return app.writeJSON(envelope{"song": song})
+	}
+
+	rows := [][]string{
+		{"id", fmt.Sprint(song.ID)},
+		{"group", song.Group},
+		{"name", song.Song},
+		{"release date", song.ReleaseDate.String()},
+		{"language", song.Language},
+		{"link", song.Link},
+		{"plays", fmt.Sprint(song.PlayCount)},
+		{"rating", formatRating(song)},
+		{"version", fmt.Sprint(song.Version)},
+	}
+
+	tw := tabwriter.NewWriter(app.out, 0, 0, 2, ' ', 0)
+	for _, row := range rows {
+		fmt.Fprintf(tw, "%s:\t%s\n", row[0], row[1])
+	}
+
+	err := tw.Flush()
+	if err != nil {
+		return err
+	}
+
+	//текст песни выводится целиком после полей
+	if song.Text != "" {
+		_, err = fmt.Fprintf(app.out, "\n%s\n", song.Text)
+	}
+
+	return err
+}
+
+func (app *application) printStats(stats *data.CatalogStats) error {
+	if app.config.format == "json" {
+		return app.writeJSON(envelope{"stats": stats})
+	}
+
+	rows := [][]string{
+		{"songs", fmt.Sprint(stats.Songs)},
+		{"groups", fmt.Sprint(stats.Groups)},
+		{"songs with text", fmt.Sprint(stats.SongsWithText)},
+		{"links", fmt.Sprint(stats.Links)},
+		{"dead links", fmt.Sprint(stats.DeadLinks)},
+		{"translations", fmt.Sprint(stats.Translations)},
+		{"users", fmt.Sprint(stats.Users)},
+		{"activated users", fmt.Sprint(stats.ActivatedUsers)},
+		{"plays", fmt.Sprint(stats.Plays)},
+		{"favorites", fmt.Sprint(stats.Favorites)},
+		{"ratings", fmt.Sprint(stats.Ratings)},
+	}
+
+	//языки по убыванию числа песен
+	languages := slices.SortedFunc(maps.Keys(stats.Languages), func(a, b string) int {
+		if stats.Languages[a] != stats.Languages[b] {
+			return int(stats.Languages[b] - stats.Languages[a])
+		}
+		return strings.Compare(a, b)
+	})
+	for _, lang := range languages {
+		rows = append(rows, []string{"language " + lang, fmt.Sprint(stats.Languages[lang])})
+	}
+
+	return app.writeTable([]string{"METRIC", "VALUE"}, rows)
+}
+
+// результат команды, меняющей данные: json для скриптов или строка для человека
+func (app *application) printResult(env envelope, message string) error {
+	if app.config.format == "json" {
+		return app.writeJSON(env)
+	}
+
+	_, err := fmt.Fprintln(app.out, message)
+	return err
+}
+
+func formatRating(song *data.Song) string {
+	if song.AverageRating == nil {
+		return "-"
+	}
+
+	return fmt.Sprintf("%.1f (%d)", *song.AverageRating, song.RatingCount)
+}
+
+func truncate(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if utf8.RuneCountInString(s) <= maxCellWidth {
+		return s
+	}
+
+	runes := []rune(s)
+	return string(runes[:maxCellWidth-1]) + "…"
+}
@@ -0,0 +1,410 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Segren/testTask/internal/data"
+	"github.com/Segren/testTask/internal/langdetect"
+	"github.com/Segren/testTask/internal/provider"
+	"github.com/Segren/testTask/internal/validator"
+)
+
+func newFlagSet(name, usage string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: musicctl %s %s\n", name, usage)
+		fs.PrintDefaults()
+	}
+
+	return fs
+}
+
+// ошибки валидатора одной строкой, ключи по алфавиту
+func validationError(errs map[string]string) error {
+	parts := make([]string, 0, len(errs))
+	for _, key := range slices.Sorted(maps.Keys(errs)) {
+		parts = append(parts, key+": "+errs[key])
+	}
+
+	return errors.New("validation failed: " + strings.Join(parts, "; "))
+}
+
+func parseID(s string) (int64, error) {
+	id, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || id < 1 {
+		return 0, fmt.Errorf("invalid song id %q", s)
+	}
+
+	return id, nil
+}
+
+// флаг с датой выхода, пустое значение означает отсутствие даты
+type dateFlag struct {
+	date *data.Date
+}
+
+func (f dateFlag) String() string {
+	if f.date == nil {
+		return ""
+	}
+	return f.date.String()
+}
+
+func (f dateFlag) Set(s string) error {
+	if s == "" {
+		*f.date = data.Date{}
+		return nil
+	}
+
+	date, err := data.ParseDate(s)
+	if err != nil {
+		return err
+	}
+
+	*f.date = date
+	return nil
+}
+
+func (app *application) listCommand(ctx context.Context, args []string) error {
+	var input struct {
+		name        string
+		group       string
+		releaseFrom data.Date
+		releaseTo   data.Date
+		data.Filters
+	}
+
+	fs := newFlagSet("list", "[flags]")
+	fs.StringVar(&input.name, "name", "", "Full text search by song name")
+	fs.StringVar(&input.group, "group", "", "Filter by group")
+	fs.Var(dateFlag{&input.releaseFrom}, "from", "Released on or after (YYYY, YYYY-MM or YYYY-MM-DD)")
+	fs.Var(dateFlag{&input.releaseTo}, "to", "Released on or before (YYYY, YYYY-MM or YYYY-MM-DD)")
+	fs.IntVar(&input.Page, "page", 1, "Page number")
+	fs.IntVar(&input.PageSize, "page-size", 20, "Songs per page (at most 100)")
+	fs.StringVar(&input.Sort, "sort", "id", "Sort field, prefix with - for descending order")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	input.SortSafelist = data.SongSortSafelist()
+
+	v := validator.New()
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		return validationError(v.Errors)
+	}
+
+	songs, metadata, err := app.models.Songs.GetAll(ctx, input.name, input.group, input.releaseFrom, input.releaseTo, input.Filters)
+	if err != nil {
+		return err
+	}
+
+	return app.printSongs(songs, metadata)
+}
+
+func (app *application) searchCommand(ctx context.Context, args []string) error {
+	filters := data.Filters{SortSafelist: data.SongSortSafelist(), Sort: "id"}
+
+	fs := newFlagSet("search", "[flags] <query>")
+	fs.IntVar(&filters.Page, "page", 1, "Page number")
+	fs.IntVar(&filters.PageSize, "page-size", 20, "Songs per page (at most 100)")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	query := strings.Join(fs.Args(), " ")
+	if strings.TrimSpace(query) == "" {
+		fs.Usage()
+		return errors.New("search query must be provided")
+	}
+
+	v := validator.New()
+	if data.ValidateFilters(v, filters); !v.Valid() {
+		return validationError(v.Errors)
+	}
+
+	songs, metadata, err := app.models.Songs.GetAll(ctx, query, "", data.Date{}, data.Date{}, filters)
+	if err != nil {
+		return err
+	}
+
+	return app.printSongs(songs, metadata)
+}
+
+func (app *application) showCommand(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: musicctl show <id>")
+	}
+
+	id, err := parseID(args[0])
+	if err != nil {
+		return err
+	}
+
+	song, err := app.models.Songs.Get(ctx, id)
+	if err != nil {
+		return songError(id, err)
+	}
+
+	return app.printSong(song)
+}
+
+func (app *application) createCommand(ctx context.Context, args []string) error {
+	song := &data.Song{}
+
+	var fetch bool
+	var textFile string
+
+	fs := newFlagSet("create", "-group <group> -song <name> [flags]")
+	fs.StringVar(&song.Group, "group", "", "Group name")
+	fs.StringVar(&song.Song, "song", "", "Song name")
+	fs.BoolVar(&fetch, "fetch", true, "Fetch release date, text and link from the info provider")
+	fs.Var(dateFlag{&song.ReleaseDate}, "release-date", "Release date when -fetch=false")
+	fs.StringVar(&song.Text, "text", "", "Song text when -fetch=false")
+	fs.StringVar(&textFile, "text-file", "", "Read song text from a file when -fetch=false")
+	fs.StringVar(&song.Link, "link", "", "Song link when -fetch=false")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if textFile != "" {
+		song.Text, err = readText(textFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	v := validator.New()
+	if data.ValidateSong(v, song); !v.Valid() {
+		return validationError(v.Errors)
+	}
+
+	if fetch {
+		err = app.applyProviderDetails(ctx, song)
+		if err != nil {
+			return err
+		}
+	}
+
+	song.Language = langdetect.Detect(song.Text)
+
+	//песня и ссылка от внешнего api сохраняются в одной транзакции
+	err = app.models.Songs.InsertMany(ctx, []*data.Song{song}, "info provider")
+	if err != nil {
+		return err
+	}
+
+	return app.printResult(envelope{"song": song}, fmt.Sprintf("created song %d", song.ID))
+}
+
+func (app *application) editCommand(ctx context.Context, args []string) error {
+	var input struct {
+		group       string
+		song        string
+		releaseDate data.Date
+		text        string
+		textFile    string
+	}
+
+	fs := newFlagSet("edit", "[flags] <id>")
+	fs.StringVar(&input.group, "group", "", "New group name")
+	fs.StringVar(&input.song, "song", "", "New song name")
+	fs.Var(dateFlag{&input.releaseDate}, "release-date", "New release date, empty value removes the date")
+	fs.StringVar(&input.text, "text", "", "New song text")
+	fs.StringVar(&input.textFile, "text-file", "", "Read new song text from a file")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("song id must be provided")
+	}
+
+	id, err := parseID(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	song, err := app.models.Songs.Get(ctx, id)
+	if err != nil {
+		return songError(id, err)
+	}
+
+	//меняются только явно заданные поля, как в PATCH /songs/:id
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	if len(set) == 0 {
+		return errors.New("nothing to change, set at least one of -group, -song, -release-date, -text, -text-file")
+	}
+
+	if set["group"] {
+		song.Group = input.group
+	}
+	if set["song"] {
+		song.Song = input.song
+	}
+	if set["release-date"] {
+		song.ReleaseDate = input.releaseDate
+	}
+	if set["text-file"] {
+		input.text, err = readText(input.textFile)
+		if err != nil {
+			return err
+		}
+	}
+	if set["text"] || set["text-file"] {
+		song.Text = input.text
+		song.Language = langdetect.Detect(song.Text)
+	}
+
+	v := validator.New()
+	if data.ValidateSong(v, song); !v.Valid() {
+		return validationError(v.Errors)
+	}
+
+	err = app.models.Songs.Update(ctx, song)
+	if err != nil {
+		if errors.Is(err, data.ErrEditConflict) {
+			return fmt.Errorf("song %d was changed concurrently, try again", id)
+		}
+		return err
+	}
+
+	return app.printResult(envelope{"song": song}, fmt.Sprintf("updated song %d", song.ID))
+}
+
+func (app *application) deleteCommand(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: musicctl delete <id>...")
+	}
+
+	ids := make([]int64, 0, len(args))
+	for _, arg := range args {
+		id, err := parseID(arg)
+		if err != nil {
+			return err
+		}
+		ids = append(ids, id)
+	}
+
+	for _, id := range ids {
+		err := app.models.Songs.Delete(ctx, id)
+		if err != nil {
+			return songError(id, err)
+		}
+
+		err = app.printResult(envelope{"deleted": id}, fmt.Sprintf("deleted song %d", id))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// заново запрашивает данные песен у внешнего api. Ошибка по одной песне не прерывает остальные
+func (app *application) enrichCommand(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: musicctl enrich <id>...")
+	}
+
+	failed := 0
+
+	for _, arg := range args {
+		err := app.enrichSong(ctx, arg)
+		if err != nil {
+			failed++
+			fmt.Fprintf(os.Stderr, "musicctl: song %s: %v\n", arg, err)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d songs were not enriched", failed, len(args))
+	}
+
+	return nil
+}
+
+func (app *application) enrichSong(ctx context.Context, arg string) error {
+	id, err := parseID(arg)
+	if err != nil {
+		return err
+	}
+
+	song, err := app.models.Songs.Get(ctx, id)
+	if err != nil {
+		return songError(id, err)
+	}
+
+	err = app.applyProviderDetails(ctx, song)
+	if err != nil {
+		return err
+	}
+
+	song.Language = langdetect.Detect(song.Text)
+
+	//ссылка от внешнего api добавляется, если ее еще нет, в той же транзакции
+	err = app.models.Songs.UpdateWithLink(ctx, song, "info provider")
+	if err != nil {
+		return err
+	}
+
+	return app.printResult(envelope{"song": song}, fmt.Sprintf("enriched song %d", song.ID))
+}
+
+// переносит в song данные внешнего api с той же проверкой, что при создании через api
+func (app *application) applyProviderDetails(ctx context.Context, song *data.Song) error {
+	detail, err := app.provider.Fetch(ctx, song.Group, song.Song)
+	if err != nil {
+		if errors.Is(err, provider.ErrNotFound) {
+			return fmt.Errorf("%s - %s: %w", song.Group, song.Song, err)
+		}
+		return fmt.Errorf("info provider: %w", err)
+	}
+
+	v := validator.New()
+	if data.ValidateSongDetail(v, song, detail); !v.Valid() {
+		return fmt.Errorf("info provider returned invalid data: %w", validationError(v.Errors))
+	}
+
+	return nil
+}
+
+func songError(id int64, err error) error {
+	if errors.Is(err, data.ErrRecordNotFound) {
+		return fmt.Errorf("song %d not found", id)
+	}
+
+	return err
+}
+
+// читает текст из файла, "-" - из stdin
+func readText(path string) (string, error) {
+	if path == "-" {
+		b, err := io.ReadAll(os.Stdin)
+		return string(b), err
+	}
+
+	b, err := os.ReadFile(path)
+	return string(b), err
+}
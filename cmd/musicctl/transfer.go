@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Segren/testTask/internal/data"
+	"github.com/Segren/testTask/internal/langdetect"
+	"github.com/Segren/testTask/internal/validator"
+)
+
+// размер страницы при выгрузке, максимум для GetAll
+const exportPageSize = 100
+
+// загружает песни из json массива в формате export в одной транзакции. id, оценки
+// и прослушивания из файла не переносятся, песни создаются заново
+func (app *application) importCommand(ctx context.Context, args []string) error {
+	var fetch bool
+
+	fs := newFlagSet("import", "[flags] <file|->")
+	fs.BoolVar(&fetch, "fetch", false, "Fetch release date, text and link from the info provider for every song")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("input file must be provided")
+	}
+
+	var r io.Reader = os.Stdin
+	if path := fs.Arg(0); path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var songs []*data.Song
+
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+
+	err = dec.Decode(&songs)
+	if err != nil {
+		return fmt.Errorf("parse input: %w", err)
+	}
+
+	//весь файл проверяется до записи, чтобы не загрузить его частично из-за опечатки
+	for i, song := range songs {
+		v := validator.New()
+		if data.ValidateSong(v, song); !v.Valid() {
+			return fmt.Errorf("song #%d (%s - %s): %w", i+1, song.Group, song.Song, validationError(v.Errors))
+		}
+	}
+
+	//данные внешнего api запрашиваются до транзакции, чтобы не держать ее открытой
+	for i, song := range songs {
+		if fetch {
+			err = app.applyProviderDetails(ctx, song)
+			if err != nil {
+				return fmt.Errorf("song #%d: %w (nothing imported)", i+1, err)
+			}
+		}
+
+		song.Language = langdetect.Detect(song.Text)
+	}
+
+	//файл загружается целиком или не загружается совсем
+	err = app.models.Songs.InsertMany(ctx, songs, "info provider")
+	if err != nil {
+		return fmt.Errorf("%w (nothing imported)", err)
+	}
+
+	imported := make([]int64, 0, len(songs))
+	for _, song := range songs {
+		imported = append(imported, song.ID)
+	}
+
+	return app.printResult(envelope{"imported": imported}, fmt.Sprintf("imported %d songs", len(imported)))
+}
+
+// выгружает песни постранично в json массив, пригодный для import
+func (app *application) exportCommand(ctx context.Context, args []string) error {
+	var output, group string
+
+	fs := newFlagSet("export", "[flags]")
+	fs.StringVar(&output, "o", "-", "Output file, - for stdout")
+	fs.StringVar(&group, "group", "", "Export only songs of the group")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	w := app.out
+	if output != "-" {
+		f, err := os.Create(output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	filters := data.Filters{Page: 1, PageSize: exportPageSize, Sort: "id", SortSafelist: []string{"id"}}
+
+	var songs []*data.Song
+
+	for {
+		page, metadata, err := app.models.Songs.GetAll(ctx, "", group, data.Date{}, data.Date{}, filters)
+		if err != nil {
+			return err
+		}
+
+		songs = append(songs, page...)
+
+		if filters.Page >= metadata.LastPage {
+			break
+		}
+		filters.Page++
+	}
+
+	if songs == nil {
+		songs = []*data.Song{}
+	}
+
+	js, err := json.MarshalIndent(songs, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "%s\n", js)
+	if err != nil {
+		return err
+	}
+
+	//при выводе в stdout сообщение испортило бы json
+	if output != "-" {
+		fmt.Fprintf(os.Stderr, "exported %d songs to %s\n", len(songs), output)
+	}
+
+	return nil
+}
+
+func (app *application) statsCommand(ctx context.Context, args []string) error {
+	if len(args) != 0 {
+		return errors.New("usage: musicctl stats")
+	}
+
+	stats, err := app.models.Stats.Get(ctx)
+	if err != nil {
+		return err
+	}
+
+	return app.printStats(stats)
+}
@@ -0,0 +1,90 @@
+// файл настроек в формате yaml. Вложенные ключи соединяются через "-" и дают имя
+// флага: db: {dsn: ...} - это db-dsn. Списки соединяются через запятую
+package configfile
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// префикс переменных окружения: флаг -db-dsn задается переменной MUSIC_DB_DSN
+const EnvPrefix = "MUSIC_"
+
+// имя переменной окружения для флага
+func EnvName(flagName string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// читает файл и возвращает значения по именам флагов
+func Load(path string) (map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config file: %w", err)
+	}
+
+	var tree map[string]any
+
+	err = yaml.Unmarshal(content, &tree)
+	if err != nil {
+		return nil, fmt.Errorf("parse config file %s: %w", path, err)
+	}
+
+	values := make(map[string]string)
+
+	err = flatten("", tree, values)
+	if err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	return values, nil
+}
+
+func flatten(prefix string, value any, values map[string]string) error {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			err := flatten(joinKey(prefix, key), item, values)
+			if err != nil {
+				return err
+			}
+		}
+	case map[any]any:
+		for key, item := range v {
+			name, ok := key.(string)
+			if !ok {
+				return fmt.Errorf("%s: keys must be strings", prefix)
+			}
+
+			err := flatten(joinKey(prefix, name), item, values)
+			if err != nil {
+				return err
+			}
+		}
+	case []any:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			switch item.(type) {
+			case map[any]any, []any:
+				return fmt.Errorf("%s: list items must be scalar values", prefix)
+			}
+			items = append(items, fmt.Sprint(item))
+		}
+		values[prefix] = strings.Join(items, ",")
+	case nil:
+		//пустое значение оставляет значение по умолчанию
+	default:
+		values[prefix] = fmt.Sprint(v)
+	}
+
+	return nil
+}
+
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "-" + key
+}
@@ -0,0 +1,347 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Segren/testTask/internal/validator"
+	"github.com/lib/pq"
+)
+
+// ключи имеют вид mk_<prefix>_<secret>, по prefix ключ находится в бд без хранения самого ключа
+const apiKeyPrefix = "mk_"
+
+// максимальный период, в течение которого старый ключ работает после ротации
+const MaxAPIKeyGracePeriod = 7 * 24 * time.Hour
+
+var apiKeyEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+type APIKeyModel struct {
+	DB *sql.DB
+}
+
+// долгоживущий ключ для сервисов. Scopes ограничивают разрешения владельца ключа
+type APIKey struct {
+	ID         int64       `json:"id"`
+	UserID     int64       `json:"-"`
+	Name       string      `json:"name"`
+	Prefix     string      `json:"prefix"`
+	Plaintext  string      `json:"key,omitempty"`
+	Hash       []byte      `json:"-"`
+	Scopes     Permissions `json:"scopes"`
+	CreatedAt  time.Time   `json:"created_at"`
+	ExpiresAt  *time.Time  `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time  `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time  `json:"revoked_at,omitempty"`
+	ReplacedBy *int64      `json:"replaced_by,omitempty"`
+}
+
+// ключ действует, если не отозван и не истек
+func (k *APIKey) Active() bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+
+	return k.ExpiresAt == nil || k.ExpiresAt.After(time.Now())
+}
+
+func generateAPIKey() (prefix, plaintext string, hash []byte, err error) {
+	randomBytes := make([]byte, 5+20)
+
+	_, err = rand.Read(randomBytes)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	prefix = apiKeyEncoding.EncodeToString(randomBytes[:5])
+	plaintext = apiKeyPrefix + prefix + "_" + apiKeyEncoding.EncodeToString(randomBytes[5:])
+
+	sum := sha256.Sum256([]byte(plaintext))
+
+	return prefix, plaintext, sum[:], nil
+}
+
+// разбирает ключ вида mk_<prefix>_<secret> и возвращает prefix
+func ParseAPIKey(plaintext string) (string, bool) {
+	rest, ok := strings.CutPrefix(plaintext, apiKeyPrefix)
+	if !ok {
+		return "", false
+	}
+
+	prefix, secret, ok := strings.Cut(rest, "_")
+	if !ok || len(prefix) != 8 || len(secret) != 32 {
+		return "", false
+	}
+
+	return prefix, true
+}
+
+func ValidateAPIKey(v *validator.Validator, key *APIKey, userPermissions Permissions) {
+	v.Check(key.Name != "", "name", "must be provided")
+	v.Check(len(key.Name) <= 100, "name", "must not be more than 100 bytes long")
+
+	v.Check(len(key.Scopes) > 0, "scopes", "must contain at least 1 scope")
+	v.Check(validator.Unique(key.Scopes), "scopes", "must not contain duplicate values")
+	for _, scope := range key.Scopes {
+		v.Check(userPermissions.Include(scope), "scopes", "must only contain permissions granted to the user")
+	}
+
+	if key.ExpiresAt != nil {
+		v.Check(key.ExpiresAt.After(time.Now()), "expires_at", "must be in the future")
+	}
+}
+
+// создает ключ и сохраняет его хеш. Открытый ключ доступен только в возвращенном значении
+func (m APIKeyModel) Insert(key *APIKey) error {
+	return insertAPIKey(context.Background(), m.DB, key)
+}
+
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func insertAPIKey(ctx context.Context, db queryRower, key *APIKey) error {
+	var err error
+
+	key.Prefix, key.Plaintext, key.Hash, err = generateAPIKey()
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO api_keys (user_id, name, prefix, hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`
+
+	args := []interface{}{key.UserID, key.Name, key.Prefix, key.Hash, pq.Array(key.Scopes), key.ExpiresAt}
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	return db.QueryRowContext(ctx, query, args...).Scan(&key.ID, &key.CreatedAt)
+}
+
+const apiKeyColumns = `api_keys.id, api_keys.user_id, api_keys.name, api_keys.prefix, api_keys.hash, api_keys.scopes,
+		api_keys.created_at, api_keys.expires_at, api_keys.last_used_at, api_keys.revoked_at, api_keys.replaced_by`
+
+func apiKeyDest(key *APIKey) []any {
+	return []any{
+		&key.ID,
+		&key.UserID,
+		&key.Name,
+		&key.Prefix,
+		&key.Hash,
+		pq.Array((*[]string)(&key.Scopes)),
+		&key.CreatedAt,
+		&key.ExpiresAt,
+		&key.LastUsedAt,
+		&key.RevokedAt,
+		&key.ReplacedBy,
+	}
+}
+
+func (m APIKeyModel) GetAllForUser(userID int64) ([]*APIKey, error) {
+	query := `
+		SELECT ` + apiKeyColumns + `
+		FROM api_keys
+		WHERE user_id = $1
+		ORDER BY id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []*APIKey{}
+
+	for rows.Next() {
+		var key APIKey
+
+		err := rows.Scan(apiKeyDest(&key)...)
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, &key)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+func (m APIKeyModel) GetForUser(userID, id int64) (*APIKey, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT ` + apiKeyColumns + `
+		FROM api_keys
+		WHERE user_id = $1 AND id = $2`
+
+	var key APIKey
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, userID, id).Scan(apiKeyDest(&key)...)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &key, nil
+}
+
+// действующий ключ и его владелец по открытому значению ключа
+func (m APIKeyModel) GetForKey(plaintext string) (*APIKey, *User, error) {
+	prefix, ok := ParseAPIKey(plaintext)
+	if !ok {
+		return nil, nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT ` + apiKeyColumns + `,
+		users.id, users.created_at, users.name, users.email, users.password_hash, users.activated, users.version
+		FROM api_keys
+		INNER JOIN users ON users.id = api_keys.user_id
+		WHERE api_keys.prefix = $1`
+
+	var key APIKey
+	var user User
+
+	dest := append(apiKeyDest(&key),
+		&user.ID,
+		&user.CreatedAt,
+		&user.Name,
+		&user.Email,
+		&user.Password.hash,
+		&user.Activated,
+		&user.Version,
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, prefix).Scan(dest...)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, nil, ErrRecordNotFound
+		default:
+			return nil, nil, err
+		}
+	}
+
+	hash := sha256.Sum256([]byte(plaintext))
+	if subtle.ConstantTimeCompare(hash[:], key.Hash) != 1 || !key.Active() {
+		return nil, nil, ErrRecordNotFound
+	}
+
+	return &key, &user, nil
+}
+
+// обновляет время последнего использования не чаще раза в минуту
+func (m APIKeyModel) TouchLastUsed(id int64) error {
+	query := `
+		UPDATE api_keys
+		SET last_used_at = NOW()
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - interval '1 minute')`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, id)
+	return err
+}
+
+func (m APIKeyModel) Revoke(userID, id int64) error {
+	query := `
+		UPDATE api_keys
+		SET revoked_at = NOW()
+		WHERE user_id = $1 AND id = $2 AND revoked_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, userID, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// создает новый ключ с теми же именем и scopes. Старый ключ продолжает работать
+// еще gracePeriod, после чего истекает
+func (m APIKeyModel) Rotate(old *APIKey, gracePeriod time.Duration) (*APIKey, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	key := &APIKey{
+		UserID:    old.UserID,
+		Name:      old.Name,
+		Scopes:    old.Scopes,
+		ExpiresAt: old.ExpiresAt,
+	}
+
+	err = insertAPIKey(ctx, tx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		UPDATE api_keys
+		SET replaced_by = $1, expires_at = LEAST(COALESCE(expires_at, 'infinity'), NOW() + $2 * interval '1 second')
+		WHERE id = $3 AND revoked_at IS NULL AND replaced_by IS NULL`
+
+	result, err := tx.ExecContext(ctx, query, key.ID, int64(gracePeriod.Seconds()), old.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	//ключ уже отозван или заменен параллельным запросом
+	if rowsAffected == 0 {
+		return nil, ErrEditConflict
+	}
+
+	return key, tx.Commit()
+}
@@ -0,0 +1,42 @@
+package data
+
+import (
+	"time"
+
+	"github.com/Segren/testTask/pkg/musicapi"
+)
+
+var (
+	ErrInvalidDate = musicapi.ErrInvalidDate
+)
+
+// дата выхода песни, общий тип с клиентом. Подробнее в pkg/musicapi
+type (
+	Date          = musicapi.Date
+	DatePrecision = musicapi.DatePrecision
+)
+
+const (
+	DatePrecisionYear  = musicapi.DatePrecisionYear
+	DatePrecisionMonth = musicapi.DatePrecisionMonth
+	DatePrecisionDay   = musicapi.DatePrecisionDay
+)
+
+// дата в формате YYYY-MM-DD, DD.MM.YYYY, YYYY-MM, MM.YYYY или YYYY
+func ParseDate(s string) (Date, error) {
+	return musicapi.ParseDate(s)
+}
+
+// отбрасывает время и лишние для указанной точности части даты
+func NewDate(t time.Time, precision DatePrecision) Date {
+	return musicapi.NewDate(t, precision)
+}
+
+// точность для записи в бд, у дат без точности считается полной
+func datePrecision(d Date) DatePrecision {
+	if d.Precision == "" {
+		return DatePrecisionDay
+	}
+
+	return d.Precision
+}
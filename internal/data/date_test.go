@@ -0,0 +1,138 @@
+package data
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Segren/testTask/internal/validator"
+)
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		input     string
+		want      string
+		precision DatePrecision
+		wantErr   bool
+	}{
+		{input: "2006", want: "2006", precision: DatePrecisionYear},
+		{input: "2006-06", want: "2006-06", precision: DatePrecisionMonth},
+		{input: "06.2006", want: "2006-06", precision: DatePrecisionMonth},
+		{input: "2006-06-19", want: "2006-06-19", precision: DatePrecisionDay},
+		{input: "19.06.2006", want: "2006-06-19", precision: DatePrecisionDay},
+		{input: "2006-06-19T10:30:00Z", want: "2006-06-19", precision: DatePrecisionDay},
+		{input: "  2006-06-19 ", want: "2006-06-19", precision: DatePrecisionDay},
+		{input: "", wantErr: true},
+		{input: "06", wantErr: true},
+		{input: "2006-13", wantErr: true},
+		{input: "2006-02-30", wantErr: true},
+		{input: "19/06/2006", wantErr: true},
+		{input: "yesterday", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			date, err := ParseDate(tt.input)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidDate) {
+					t.Fatalf("ParseDate(%q): got %v, %v; want ErrInvalidDate", tt.input, date, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseDate(%q): %v", tt.input, err)
+			}
+
+			if !date.Valid || date.String() != tt.want || date.Precision != tt.precision {
+				t.Errorf("ParseDate(%q) = %q (%s), want %q (%s)", tt.input, date.String(), date.Precision, tt.want, tt.precision)
+			}
+		})
+	}
+}
+
+func TestDateEnd(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"2024", "2024-12-31"},
+		{"2024-02", "2024-02-29"},
+		{"2023-02", "2023-02-28"},
+		{"2024-11-22", "2024-11-22"},
+	}
+
+	for _, tt := range tests {
+		date, err := ParseDate(tt.input)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := date.End().String(); got != tt.want {
+			t.Errorf("ParseDate(%q).End() = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestDateJSON(t *testing.T) {
+	var song struct {
+		ReleaseDate Date `json:"releaseDate"`
+	}
+
+	for _, input := range []string{`"2006"`, `"2006-06"`, `"2006-06-19"`, `null`} {
+		err := json.Unmarshal([]byte(`{"releaseDate": `+input+`}`), &song)
+		if err != nil {
+			t.Fatalf("unmarshal %s: %v", input, err)
+		}
+
+		got, err := json.Marshal(song.ReleaseDate)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != input {
+			t.Errorf("round trip of %s gave %s", input, got)
+		}
+	}
+
+	err := json.Unmarshal([]byte(`{"releaseDate": 2006}`), &song)
+	if !errors.Is(err, ErrInvalidDate) {
+		t.Errorf("number date: got %v, want ErrInvalidDate", err)
+	}
+}
+
+func TestDatePrecisionForStorage(t *testing.T) {
+	//дата без точности (например, собранная вручную) сохраняется как полная
+	d := Date{Time: time.Date(2024, 11, 22, 0, 0, 0, 0, time.UTC), Valid: true}
+	if got := datePrecision(d); got != DatePrecisionDay {
+		t.Errorf("got %q, want %q", got, DatePrecisionDay)
+	}
+}
+
+func TestValidateSongDetailReleaseDate(t *testing.T) {
+	tests := []struct {
+		releaseDate string
+		want        string
+		valid       bool
+	}{
+		{"2006-06-19", "2006-06-19", true},
+		{"2006", "2006", true},
+		//внешнее api не знает дату: песня сохраняется без нее
+		{"", "", true},
+		{"   ", "", true},
+		{"someday", "", false},
+	}
+
+	for _, tt := range tests {
+		song := &Song{Group: "Muse", Song: "Supermassive Black Hole"}
+		v := validator.New()
+
+		ValidateSongDetail(v, song, &SongDetail{ReleaseDate: tt.releaseDate, Text: "Ooh baby", Link: "https://example.com"})
+
+		if v.Valid() != tt.valid {
+			t.Errorf("releaseDate %q: got valid %t (%v), want %t", tt.releaseDate, v.Valid(), v.Errors, tt.valid)
+		}
+		if tt.valid && song.ReleaseDate.String() != tt.want {
+			t.Errorf("releaseDate %q: got %q, want %q", tt.releaseDate, song.ReleaseDate.String(), tt.want)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+type FavoriteModel struct {
+	DB *sql.DB
+}
+
+// добавляет песню в избранное, повторное добавление ничего не меняет
+func (m FavoriteModel) Add(userID, songID int64) error {
+	query := `
+		INSERT INTO favorites (user_id, song_id)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, userID, songID)
+	if err != nil {
+		switch {
+		case isForeignKeyViolation(err, "favorites_song_id_fkey"):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m FavoriteModel) Remove(userID, songID int64) error {
+	query := `
+		DELETE FROM favorites
+		WHERE user_id = $1 AND song_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, userID, songID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// избранные песни пользователя, по умолчанию сначала добавленные последними
+func (m FavoriteModel) GetAllForUser(userID int64, filters Filters) ([]*Song, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT count(*) OVER(), songs.id, songs.created_at, songs.name, songs."group", songs.releaseDate,
+		songs.release_date_precision, songs.text, songs.link, songs.language,
+		songs.rating_sum::float8 / NULLIF(songs.rating_count, 0), songs.rating_count, songs.play_count, songs.version
+		FROM favorites
+		INNER JOIN songs ON songs.id = favorites.song_id
+		WHERE favorites.user_id = $1
+		ORDER BY favorites.created_at %s, songs.id ASC
+		LIMIT $2 OFFSET $3`, filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	songs := []*Song{}
+
+	for rows.Next() {
+		var song Song
+
+		err := rows.Scan(
+			&totalRecords,
+			&song.ID,
+			&song.CreatedAt,
+			&song.Song,
+			&song.Group,
+			&song.ReleaseDate,
+			&song.ReleaseDate.Precision,
+			&song.Text,
+			&song.Link,
+			&song.Language,
+			&song.AverageRating,
+			&song.RatingCount,
+			&song.PlayCount,
+			&song.Version,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		songs = append(songs, &song)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calcualteMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return songs, metadata, nil
+}
@@ -2,7 +2,11 @@ package data
 
 import (
 	"math"
+	"slices"
 	"strings"
+
+	"github.com/Segren/testTask/internal/validator"
+	"github.com/Segren/testTask/pkg/musicapi"
 )
 
 type Filters struct {
@@ -12,13 +16,21 @@ type Filters struct {
 	SortSafelist []string
 }
 
-// метадата для пагинации
-type Metadata struct {
-	CurrentPage  int `json:"currentPage,omitempty"`
-	PageSize     int `json:"page_size,omitempty"`
-	FirstPage    int `json:"first_page,omitempty"`
-	LastPage     int `json:"last_page,omitempty"`
-	TotalRecords int `json:"total_records,omitempty"`
+// допустимые значения sort для списка песен, с "-" по убыванию
+func SongSortSafelist() []string {
+	return []string{"id", "group", "name", "releaseDate", "popularity", "rating", "-id", "-group", "-name", "-releaseDate", "-popularity", "-rating"}
+}
+
+// метадата для пагинации, общий тип с клиентом
+type Metadata = musicapi.Metadata
+
+func ValidateFilters(v *validator.Validator, f Filters) {
+	v.Check(f.Page > 0, "page", "must be greater than zero")
+	v.Check(f.Page <= 10_000_000, "page", "must be a maximum of 10 million")
+	v.Check(f.PageSize > 0, "page_size", "must be greater than zero")
+	v.Check(f.PageSize <= 100, "page_size", "must be a maximum of 100")
+
+	v.Check(slices.Contains(f.SortSafelist, f.Sort), "sort", "invalid sort value")
 }
 
 func (f Filters) sortColumn() string {
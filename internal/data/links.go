@@ -0,0 +1,310 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Segren/testTask/internal/validator"
+
+	"github.com/Segren/testTask/pkg/musicapi"
+)
+
+var (
+	ErrDuplicateLink = errors.New("duplicate link")
+)
+
+// допустимые типы ссылок
+var LinkTypes = []string{"youtube", "spotify", "apple_music", "yandex_music", "lyrics", "other"}
+
+type LinkModel struct {
+	DB *sql.DB
+}
+
+// внешняя ссылка на песню. Dead выставляется фоновой проверкой после нескольких неудач подряд, общий тип с клиентом
+type Link = musicapi.Link
+
+func ValidateLink(v *validator.Validator, link *Link) {
+	v.Check(link.Type != "", "type", "must be provided")
+	v.Check(validator.PermittedValue(link.Type, LinkTypes...), "type", "must be one of the supported link types")
+
+	v.Check(link.URL != "", "url", "must be provided")
+	v.Check(len(link.URL) <= 2048, "url", "must not be more than 2048 bytes long")
+	v.Check(validator.IsURL(link.URL), "url", "must be an absolute http or https URL")
+
+	v.Check(len(link.AddedBy) <= 500, "added_by", "must not be more than 500 bytes long")
+}
+
+func (m LinkModel) Insert(ctx context.Context, link *Link) error {
+	return insertLink(ctx, m.DB, link)
+}
+
+func insertLink(ctx context.Context, db queryRower, link *Link) error {
+	query := `
+		INSERT INTO song_links (song_id, type, url, added_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, version`
+
+	args := []interface{}{link.SongID, link.Type, link.URL, link.AddedBy}
+
+	ctx, span := startQuerySpan(ctx, "LinkModel.Insert")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := db.QueryRowContext(ctx, query, args...).Scan(&link.ID, &link.CreatedAt, &link.Version)
+	if err != nil {
+		switch {
+		case isUniqueViolation(err, "song_links_song_id_url_key"):
+			return ErrDuplicateLink
+		default:
+			span.RecordError(err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// добавляет ссылку, если у песни ее еще нет. В отличие от insertLink дубликат не прерывает транзакцию
+func insertLinkIfMissing(ctx context.Context, db execer, link *Link) error {
+	query := `
+		INSERT INTO song_links (song_id, type, url, added_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (song_id, url) DO NOTHING`
+
+	args := []interface{}{link.SongID, link.Type, link.URL, link.AddedBy}
+
+	ctx, span := startQuerySpan(ctx, "LinkModel.InsertIfMissing")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	_, err := db.ExecContext(ctx, query, args...)
+	span.RecordError(err)
+
+	return err
+}
+
+func (m LinkModel) Get(ctx context.Context, songID, id int64) (*Link, error) {
+	if songID < 1 || id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT id, song_id, type, url, added_by, created_at, last_checked_at, failure_count, dead, version
+		FROM song_links
+		WHERE song_id = $1 AND id = $2`
+
+	var link Link
+
+	ctx, span := startQuerySpan(ctx, "LinkModel.Get")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, songID, id).Scan(
+		&link.ID,
+		&link.SongID,
+		&link.Type,
+		&link.URL,
+		&link.AddedBy,
+		&link.CreatedAt,
+		&link.LastCheckedAt,
+		&link.FailureCount,
+		&link.Dead,
+		&link.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			span.RecordError(err)
+			return nil, err
+		}
+	}
+
+	return &link, nil
+}
+
+// ссылки песни, linkType == "" и dead == nil означают отсутствие фильтра
+func (m LinkModel) GetAllForSong(ctx context.Context, songID int64, linkType string, dead *bool) ([]*Link, error) {
+	query := `
+		SELECT id, song_id, type, url, added_by, created_at, last_checked_at, failure_count, dead, version
+		FROM song_links
+		WHERE song_id = $1
+		AND (type = $2 OR $2 = '')
+		AND (dead = $3 OR $3 IS NULL)
+		ORDER BY id`
+
+	ctx, span := startQuerySpan(ctx, "LinkModel.GetAllForSong")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, songID, linkType, dead)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	links, err := scanLinks(rows)
+	span.RecordError(err)
+
+	return links, err
+}
+
+func (m LinkModel) Update(ctx context.Context, link *Link) error {
+	query := `
+		UPDATE song_links
+		SET type = $1, url = $2, failure_count = $3, dead = $4, version = version + 1
+		WHERE id = $5 AND version = $6
+		RETURNING version`
+
+	args := []interface{}{link.Type, link.URL, link.FailureCount, link.Dead, link.ID, link.Version}
+
+	ctx, span := startQuerySpan(ctx, "LinkModel.Update")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&link.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		case isUniqueViolation(err, "song_links_song_id_url_key"):
+			return ErrDuplicateLink
+		default:
+			span.RecordError(err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m LinkModel) Delete(ctx context.Context, songID, id int64) error {
+	if songID < 1 || id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+		DELETE FROM song_links
+		WHERE song_id = $1 AND id = $2`
+
+	ctx, span := startQuerySpan(ctx, "LinkModel.Delete")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, songID, id)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// ссылки, которые не проверялись дольше interval, начиная с самых старых
+func (m LinkModel) GetDueForCheck(ctx context.Context, interval time.Duration, limit int) ([]*Link, error) {
+	query := `
+		SELECT id, song_id, type, url, added_by, created_at, last_checked_at, failure_count, dead, version
+		FROM song_links
+		WHERE last_checked_at IS NULL OR last_checked_at < $1
+		ORDER BY last_checked_at NULLS FIRST, id
+		LIMIT $2`
+
+	ctx, span := startQuerySpan(ctx, "LinkModel.GetDueForCheck")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, time.Now().Add(-interval), limit)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	links, err := scanLinks(rows)
+	span.RecordError(err)
+
+	return links, err
+}
+
+// сохраняет результат проверки: успех сбрасывает счетчик неудач,
+// после maxFailures неудач подряд ссылка помечается мертвой
+func (m LinkModel) RecordCheck(ctx context.Context, id int64, ok bool, maxFailures int) error {
+	query := `
+		UPDATE song_links
+		SET last_checked_at = NOW(),
+			failure_count = CASE WHEN $2 THEN 0 ELSE failure_count + 1 END,
+			dead = CASE WHEN $2 THEN false ELSE failure_count + 1 >= $3 END
+		WHERE id = $1`
+
+	ctx, span := startQuerySpan(ctx, "LinkModel.RecordCheck")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, id, ok, maxFailures)
+	span.RecordError(err)
+
+	return err
+}
+
+func scanLinks(rows *sql.Rows) ([]*Link, error) {
+	links := []*Link{}
+
+	for rows.Next() {
+		var link Link
+
+		err := rows.Scan(
+			&link.ID,
+			&link.SongID,
+			&link.Type,
+			&link.URL,
+			&link.AddedBy,
+			&link.CreatedAt,
+			&link.LastCheckedAt,
+			&link.FailureCount,
+			&link.Dead,
+			&link.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		links = append(links, &link)
+	}
+
+	//проверка ошибок итерации
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return links, nil
+}
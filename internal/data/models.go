@@ -4,6 +4,8 @@ package data
 import (
 	"database/sql"
 	"errors"
+
+	"github.com/lib/pq"
 )
 
 var (
@@ -12,11 +14,55 @@ var (
 )
 
 type Models struct {
-	Songs SongModel
+	Songs         SongModel
+	Links         LinkModel
+	Translations  TranslationModel
+	ProviderCache ProviderCacheModel
+	Users         UserModel
+	Tokens        TokenModel
+	Permissions   PermissionModel
+	APIKeys       APIKeyModel
+	Favorites     FavoriteModel
+	Ratings       RatingModel
+	Plays         PlayModel
+	Similarity    SimilarityModel
+	Schema        SchemaModel
+	Stats         StatsModel
 }
 
 func NewModels(db *sql.DB) Models {
 	return Models{
-		Songs: SongModel{DB: db},
+		Songs:         SongModel{DB: db},
+		Links:         LinkModel{DB: db},
+		Translations:  TranslationModel{DB: db},
+		ProviderCache: ProviderCacheModel{DB: db},
+		Users:         UserModel{DB: db},
+		Tokens:        TokenModel{DB: db},
+		Permissions:   PermissionModel{DB: db},
+		APIKeys:       APIKeyModel{DB: db},
+		Favorites:     FavoriteModel{DB: db},
+		Ratings:       RatingModel{DB: db},
+		Plays:         PlayModel{DB: db},
+		Similarity:    SimilarityModel{DB: db},
+		Schema:        SchemaModel{DB: db},
+		Stats:         StatsModel{DB: db},
+	}
+}
+
+// нарушение уникального ограничения constraint
+func isUniqueViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "23505" && pqErr.Constraint == constraint
+	}
+	return false
+}
+
+// нарушение внешнего ключа constraint, например ссылка на удаленную песню
+func isForeignKeyViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "23503" && pqErr.Constraint == constraint
 	}
+	return false
 }
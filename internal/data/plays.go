@@ -0,0 +1,80 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Segren/testTask/pkg/musicapi"
+)
+
+type PlayModel struct {
+	DB *sql.DB
+}
+
+// прослушивание песни пользователем, общий тип с клиентом
+type Play = musicapi.Play
+
+func (m PlayModel) Insert(userID int64, play *Play) error {
+	query := `
+		INSERT INTO plays (user_id, song_id)
+		VALUES ($1, $2)
+		RETURNING id, played_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, userID, play.SongID).Scan(&play.ID, &play.PlayedAt)
+	if err != nil {
+		switch {
+		case isForeignKeyViolation(err, "plays_song_id_fkey"):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// история прослушиваний пользователя, сначала последние
+func (m PlayModel) GetAllForUser(userID int64, filters Filters) ([]*Play, Metadata, error) {
+	query := `
+		SELECT count(*) OVER(), plays.id, plays.song_id, songs."group", songs.name, plays.played_at
+		FROM plays
+		INNER JOIN songs ON songs.id = plays.song_id
+		WHERE plays.user_id = $1
+		ORDER BY plays.played_at DESC, plays.id DESC
+		LIMIT $2 OFFSET $3`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	plays := []*Play{}
+
+	for rows.Next() {
+		var play Play
+
+		err := rows.Scan(&totalRecords, &play.ID, &play.SongID, &play.Group, &play.Song, &play.PlayedAt)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		plays = append(plays, &play)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calcualteMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return plays, metadata, nil
+}
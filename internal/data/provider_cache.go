@@ -0,0 +1,91 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+type ProviderCacheModel struct {
+	DB *sql.DB
+}
+
+// закешированный ответ внешнего api. Detail == nil если api ответило 404
+type ProviderCacheEntry struct {
+	Key       string
+	Detail    *SongDetail
+	NotFound  bool
+	ExpiresAt time.Time
+}
+
+func (m ProviderCacheModel) Get(key string) (*ProviderCacheEntry, error) {
+	query := `
+		SELECT payload, not_found, expires_at
+		FROM provider_cache
+		WHERE key = $1 AND expires_at > NOW()`
+
+	entry := ProviderCacheEntry{Key: key}
+	var payload []byte
+
+	//контекст для прерывания запроса который длится дольше 3 секунд
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, key).Scan(&payload, &entry.NotFound, &entry.ExpiresAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	if !entry.NotFound {
+		entry.Detail = &SongDetail{}
+		err = json.Unmarshal(payload, entry.Detail)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &entry, nil
+}
+
+func (m ProviderCacheModel) Set(entry *ProviderCacheEntry) error {
+	query := `
+		INSERT INTO provider_cache (key, payload, not_found, expires_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (key) DO UPDATE
+		SET payload = EXCLUDED.payload, not_found = EXCLUDED.not_found, expires_at = EXCLUDED.expires_at`
+
+	payload, err := json.Marshal(entry.Detail)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err = m.DB.ExecContext(ctx, query, entry.Key, payload, entry.NotFound, entry.ExpiresAt)
+	return err
+}
+
+// удаляет просроченные записи, Get их уже не отдает, но они занимают место в таблице
+func (m ProviderCacheModel) DeleteExpired() (int64, error) {
+	query := `
+		DELETE FROM provider_cache
+		WHERE expires_at < NOW()`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
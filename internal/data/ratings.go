@@ -0,0 +1,133 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Segren/testTask/internal/validator"
+
+	"github.com/Segren/testTask/pkg/musicapi"
+)
+
+type RatingModel struct {
+	DB *sql.DB
+}
+
+// оценка песни пользователем от 1 до 5, общий тип с клиентом
+type Rating = musicapi.Rating
+
+func ValidateRating(v *validator.Validator, rating int) {
+	v.Check(rating >= 1 && rating <= 5, "rating", "must be between 1 and 5")
+}
+
+// ставит оценку или заменяет предыдущую оценку пользователя
+func (m RatingModel) Upsert(userID int64, rating *Rating) error {
+	query := `
+		INSERT INTO ratings (user_id, song_id, rating)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, song_id) DO UPDATE
+		SET rating = EXCLUDED.rating, updated_at = NOW()
+		RETURNING updated_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, userID, rating.SongID, rating.Rating).Scan(&rating.UpdatedAt)
+	if err != nil {
+		switch {
+		case isForeignKeyViolation(err, "ratings_song_id_fkey"):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m RatingModel) Get(userID, songID int64) (*Rating, error) {
+	query := `
+		SELECT song_id, rating, updated_at
+		FROM ratings
+		WHERE user_id = $1 AND song_id = $2`
+
+	var rating Rating
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, userID, songID).Scan(&rating.SongID, &rating.Rating, &rating.UpdatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &rating, nil
+}
+
+func (m RatingModel) GetAllForUser(userID int64) ([]*Rating, error) {
+	query := `
+		SELECT song_id, rating, updated_at
+		FROM ratings
+		WHERE user_id = $1
+		ORDER BY updated_at DESC, song_id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ratings := []*Rating{}
+
+	for rows.Next() {
+		var rating Rating
+
+		err := rows.Scan(&rating.SongID, &rating.Rating, &rating.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		ratings = append(ratings, &rating)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ratings, nil
+}
+
+func (m RatingModel) Delete(userID, songID int64) error {
+	query := `
+		DELETE FROM ratings
+		WHERE user_id = $1 AND song_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, userID, songID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,28 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+type SchemaModel struct {
+	DB *sql.DB
+}
+
+func (m SchemaModel) Ping(ctx context.Context) error {
+	return m.DB.PingContext(ctx)
+}
+
+// текущая версия схемы из таблицы migrate. dirty - последняя миграция завершилась с ошибкой.
+// До первой миграции версия 0
+func (m SchemaModel) Version(ctx context.Context) (version int64, dirty bool, err error) {
+	query := `SELECT version, dirty FROM schema_migrations LIMIT 1`
+
+	err = m.DB.QueryRowContext(ctx, query).Scan(&version, &dirty)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+
+	return version, dirty, err
+}
@@ -0,0 +1,198 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/Segren/testTask/pkg/musicapi"
+)
+
+type SimilarityModel struct {
+	DB *sql.DB
+}
+
+// похожая песня с объяснением, почему она похожа, общий тип с клиентом
+type SimilarSong = musicapi.SimilarSong
+
+// рекомендованная песня и песня из библиотеки пользователя, из-за которой она рекомендована, общий тип с клиентом
+type Recommendation = musicapi.Recommendation
+
+// пересчитывает таблицу похожих песен. Чтение во время пересчета не блокируется.
+// Стоимость пересчета ограничена определением song_similarity (миграция 000011): не больше 50 пар из своей группы на песню
+// и 200 последних песен на пользователя, иначе она росла бы квадратично с размером групп
+func (m SimilarityModel) Refresh(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, `REFRESH MATERIALIZED VIEW CONCURRENTLY song_similarity`)
+	return err
+}
+
+func (m SimilarityModel) GetSimilar(songID int64, limit int) ([]*SimilarSong, error) {
+	query := `
+		SELECT songs.id, songs."group", songs.name, song_similarity.score,
+		song_similarity.co_listeners, song_similarity.same_group, song_similarity.same_language
+		FROM song_similarity
+		INNER JOIN songs ON songs.id = song_similarity.similar_song_id
+		WHERE song_similarity.song_id = $1
+		ORDER BY song_similarity.score DESC, songs.id
+		LIMIT $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, songID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	songs := []*SimilarSong{}
+
+	for rows.Next() {
+		var song SimilarSong
+		var coListeners int
+		var sameGroup, sameLanguage bool
+
+		err := rows.Scan(&song.ID, &song.Group, &song.Song, &song.Score, &coListeners, &sameGroup, &sameLanguage)
+		if err != nil {
+			return nil, err
+		}
+
+		song.Reasons = []string{}
+		if coListeners > 0 {
+			song.Reasons = append(song.Reasons, fmt.Sprintf("listened together by %d users", coListeners))
+		}
+		if sameGroup {
+			song.Reasons = append(song.Reasons, "same group")
+		}
+		if sameLanguage {
+			song.Reasons = append(song.Reasons, "same language")
+		}
+
+		songs = append(songs, &song)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return songs, nil
+}
+
+// песни, похожие на недавно прослушанные, избранные и высоко оцененные пользователем.
+// Уже прослушанные, избранные и оцененные (в т.ч. низко) песни не рекомендуются
+func (m SimilarityModel) GetRecommendations(userID int64, limit int) ([]*Recommendation, error) {
+	query := `
+		WITH seeds AS (
+			SELECT song_id, 1.0 AS weight, 'listened to' AS action
+			FROM (
+				SELECT song_id FROM plays WHERE user_id = $1
+				GROUP BY song_id ORDER BY max(played_at) DESC LIMIT 50
+			) recent
+			UNION ALL
+			SELECT song_id, 1.5, 'added to favorites' FROM favorites WHERE user_id = $1
+			UNION ALL
+			SELECT song_id, rating - 3, 'rated' FROM ratings WHERE user_id = $1 AND rating >= 4
+		),
+		candidates AS (
+			SELECT song_similarity.similar_song_id AS song_id, seeds.song_id AS seed_id, seeds.action,
+			song_similarity.score * seeds.weight AS contribution
+			FROM seeds
+			INNER JOIN song_similarity ON song_similarity.song_id = seeds.song_id
+			WHERE NOT EXISTS (SELECT 1 FROM plays WHERE plays.user_id = $1 AND plays.song_id = song_similarity.similar_song_id)
+			AND NOT EXISTS (SELECT 1 FROM favorites WHERE favorites.user_id = $1 AND favorites.song_id = song_similarity.similar_song_id)
+			AND NOT EXISTS (SELECT 1 FROM ratings WHERE ratings.user_id = $1 AND ratings.song_id = song_similarity.similar_song_id)
+		),
+		ranked AS (
+			SELECT song_id, sum(contribution) AS score,
+			(array_agg(seed_id ORDER BY contribution DESC))[1] AS seed_id,
+			(array_agg(action ORDER BY contribution DESC))[1] AS action
+			FROM candidates
+			GROUP BY song_id
+		)
+		SELECT songs.id, songs."group", songs.name, ranked.score, ranked.action, seed."group", seed.name
+		FROM ranked
+		INNER JOIN songs ON songs.id = ranked.song_id
+		INNER JOIN songs seed ON seed.id = ranked.seed_id
+		ORDER BY ranked.score DESC, songs.id
+		LIMIT $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	recommendations := []*Recommendation{}
+
+	for rows.Next() {
+		var rec Recommendation
+		var action, seedGroup, seedSong string
+
+		err := rows.Scan(&rec.ID, &rec.Group, &rec.Song, &rec.Score, &action, &seedGroup, &seedSong)
+		if err != nil {
+			return nil, err
+		}
+
+		rec.Reason = fmt.Sprintf("because you %s %s - %s", action, seedGroup, seedSong)
+
+		recommendations = append(recommendations, &rec)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return recommendations, nil
+}
+
+// самые популярные песни, которые пользователь еще не слушал, не добавил в избранное и не оценил.
+// Для пользователей без истории
+func (m SimilarityModel) GetPopular(userID int64, limit int) ([]*Recommendation, error) {
+	query := `
+		SELECT id, "group", name, play_count
+		FROM songs
+		WHERE play_count > 0
+		AND NOT EXISTS (SELECT 1 FROM plays WHERE plays.user_id = $1 AND plays.song_id = songs.id)
+		AND NOT EXISTS (SELECT 1 FROM favorites WHERE favorites.user_id = $1 AND favorites.song_id = songs.id)
+		AND NOT EXISTS (SELECT 1 FROM ratings WHERE ratings.user_id = $1 AND ratings.song_id = songs.id)
+		ORDER BY play_count DESC, id
+		LIMIT $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	recommendations := []*Recommendation{}
+
+	for rows.Next() {
+		var rec Recommendation
+		var plays int64
+
+		err := rows.Scan(&rec.ID, &rec.Group, &rec.Song, &plays)
+		if err != nil {
+			return nil, err
+		}
+
+		rec.Reason = fmt.Sprintf("popular: played %d times", plays)
+
+		recommendations = append(recommendations, &rec)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return recommendations, nil
+}
@@ -7,23 +7,37 @@ import (
 	"fmt"
 	"strings"
 	"time"
+	"unicode/utf8"
 
+	"github.com/Segren/testTask/internal/tracing"
 	"github.com/Segren/testTask/internal/validator"
+	"github.com/Segren/testTask/pkg/musicapi"
+	"golang.org/x/text/unicode/norm"
 )
 
+// максимальный размер текста песни
+const maxLyricsBytes = 100_000
+
 type SongModel struct {
 	DB *sql.DB
 }
 
-type Song struct {
-	ID          int64     `json:"id"`
-	CreatedAt   time.Time `json:"-"`
-	Group       string    `json:"group"`
-	Song        string    `json:"name"`
-	ReleaseDate string    `json:"releaseDate"`
-	Text        string    `json:"text"`
-	Link        string    `json:"link"`
-	Version     int32     `json:"version"`
+// спан запроса к бд, дочерний к спану из ctx
+func startQuerySpan(ctx context.Context, name string) (context.Context, *tracing.Span) {
+	ctx, span := tracing.Start(ctx, name)
+	span.SetAttribute("db.system", "postgresql")
+
+	return ctx, span
+}
+
+// песня в том виде, в котором ее отдает api, общий тип с клиентом
+type Song = musicapi.Song
+
+// данные о песне в том виде, в котором их вернуло внешнее api
+type SongDetail struct {
+	ReleaseDate string `json:"releaseDate"`
+	Text        string `json:"text"`
+	Link        string `json:"link"`
 }
 
 type SongsResponse struct {
@@ -31,37 +45,99 @@ type SongsResponse struct {
 	Metadata Metadata `json:"metadata"`
 }
 
-func (m SongModel) Insert(song *Song) error {
+func (m SongModel) Insert(ctx context.Context, song *Song) error {
+	return insertSong(ctx, m.DB, song)
+}
+
+func insertSong(ctx context.Context, db queryRower, song *Song) error {
 	query := `
-	    INSERT INTO songs ("group", name, releaseDate, text, link)
-		VALUES ($1, $2, $3, $4, $5)
+	    INSERT INTO songs ("group", name, releaseDate, release_date_precision, text, link, language)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 		RETURNING id, created_at, version`
 
-	args := []interface{}{song.Group, song.Song, song.ReleaseDate, song.Text, song.Link}
+	args := []interface{}{song.Group, song.Song, song.ReleaseDate, datePrecision(song.ReleaseDate), song.Text, song.Link, song.Language}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, span := startQuerySpan(ctx, "SongModel.Insert")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
-	return m.DB.QueryRowContext(ctx, query, args...).Scan(&song.ID, &song.CreatedAt, &song.Version)
+	err := db.QueryRowContext(ctx, query, args...).Scan(&song.ID, &song.CreatedAt, &song.Version)
+	span.RecordError(err)
+
+	return err
 }
 
-func (m SongModel) GetAll(name string, group string, filters Filters) ([]*Song, Metadata, error) {
+// добавляет песни в одной транзакции: при ошибке не сохраняется ни одна.
+// Непустая song.Link также добавляется к ссылкам песни с типом other от linkAddedBy
+func (m SongModel) InsertMany(ctx context.Context, songs []*Song, linkAddedBy string) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for i, song := range songs {
+		err = insertSong(ctx, tx, song)
+		if err != nil {
+			return fmt.Errorf("song #%d: %w", i+1, err)
+		}
+
+		if song.Link == "" {
+			continue
+		}
+
+		err = insertLink(ctx, tx, &Link{SongID: song.ID, Type: "other", URL: song.Link, AddedBy: linkAddedBy})
+		if err != nil {
+			return fmt.Errorf("song #%d link: %w", i+1, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// выражения сортировки для значений sort, не совпадающих с именем колонки
+var songSortColumns = map[string]string{
+	"group":      `"group"`,
+	"popularity": "play_count",
+	"rating":     "COALESCE(rating_sum::float8 / NULLIF(rating_count, 0), 0)",
+}
+
+func songSortColumn(f Filters) string {
+	column := f.sortColumn()
+	if expr, ok := songSortColumns[column]; ok {
+		return expr
+	}
+
+	return column
+}
+
+// releaseFrom и releaseTo ограничивают дату выхода, невалидная дата означает отсутствие ограничения
+func (m SongModel) GetAll(ctx context.Context, name string, group string, releaseFrom, releaseTo Date, filters Filters) ([]*Song, Metadata, error) {
 	query := fmt.Sprintf(`
-		SELECT count(*) OVER(), id, created_at, name, "group", releaseDate, text, link, version
+		SELECT count(*) OVER(), id, created_at, name, "group", releaseDate, release_date_precision, text, link, language,
+		rating_sum::float8 / NULLIF(rating_count, 0), rating_count, play_count, version
 		FROM songs
 		WHERE (to_tsvector('simple', name) @@ plainto_tsquery('simple', $1) OR $1 = '')
 		AND ("group" = $2 OR $2 = '')
+		AND (releaseDate >= $3::date OR $3 IS NULL)
+		AND (releaseDate <= $4::date OR $4 IS NULL)
 		ORDER BY %s %s, id ASC
-		LIMIT $3 OFFSET $4`, filters.sortColumn(), filters.sortDirection())
+		LIMIT $5 OFFSET $6`, songSortColumn(filters), filters.sortDirection())
+
+	ctx, span := startQuerySpan(ctx, "SongModel.GetAll")
+	defer span.End()
 
 	//контекст для прерывания запроса который длится дольше 3 секунд
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
-	args := []interface{}{name, group, filters.limit(), filters.offset()}
+	args := []interface{}{name, group, releaseFrom, releaseTo.End(), filters.limit(), filters.offset()}
 
 	rows, err := m.DB.QueryContext(ctx, query, args...)
 	if err != nil {
+		span.RecordError(err)
 		return nil, Metadata{}, err
 	}
 
@@ -80,11 +156,17 @@ func (m SongModel) GetAll(name string, group string, filters Filters) ([]*Song,
 			&song.Song,
 			&song.Group,
 			&song.ReleaseDate,
+			&song.ReleaseDate.Precision,
 			&song.Text,
 			&song.Link,
+			&song.Language,
+			&song.AverageRating,
+			&song.RatingCount,
+			&song.PlayCount,
 			&song.Version,
 		)
 		if err != nil {
+			span.RecordError(err)
 			return nil, Metadata{}, err
 		}
 
@@ -93,6 +175,7 @@ func (m SongModel) GetAll(name string, group string, filters Filters) ([]*Song,
 
 	//проверка ошибок итерации
 	if err = rows.Err(); err != nil {
+		span.RecordError(err)
 		return nil, Metadata{}, err
 	}
 
@@ -108,9 +191,45 @@ func ValidateSong(v *validator.Validator, song *Song) {
 
 	v.Check(song.Group != "", "group", "must be provided")
 	v.Check(len(song.Group) <= 5000, "group", "must not be more than 5000 bytes long")
+
+	if song.ReleaseDate.Valid {
+		ValidateReleaseDate(v, "releaseDate", song.ReleaseDate)
+	}
+
+	v.Check(len(song.Text) <= maxLyricsBytes, "text", fmt.Sprintf("must not be more than %d bytes long", maxLyricsBytes))
+
+	if song.Link != "" {
+		v.Check(len(song.Link) <= 2048, "link", "must not be more than 2048 bytes long")
+		v.Check(validator.IsURL(song.Link), "link", "must be an absolute http or https URL")
+	}
+}
+
+// дата выхода не может быть раньше звукозаписи и позже следующего года
+func ValidateReleaseDate(v *validator.Validator, key string, date Date) {
+	v.Check(date.Time.Year() >= 1800, key, "must not be before 1800")
+	v.Check(date.Time.Year() <= time.Now().Year()+1, key, "must not be more than a year in the future")
+}
+
+// проверяет и нормализует данные, полученные из внешнего api, и переносит их в song
+func ValidateSongDetail(v *validator.Validator, song *Song, detail *SongDetail) {
+	//дата выхода необязательна: пустая строка сохраняется как NULL
+	var releaseDate Date
+	if s := strings.TrimSpace(detail.ReleaseDate); s != "" {
+		var err error
+		releaseDate, err = ParseDate(s)
+		v.Check(err == nil, "releaseDate", "must be a valid date in YYYY-MM-DD, DD.MM.YYYY, YYYY-MM or YYYY format")
+	}
+
+	v.Check(utf8.ValidString(detail.Text), "text", "must be valid UTF-8")
+
+	song.ReleaseDate = releaseDate
+	song.Text = normalizeText(detail.Text)
+	song.Link = strings.TrimSpace(detail.Link)
+
+	ValidateSong(v, song)
 }
 
-func (m SongModel) Delete(id int64) error {
+func (m SongModel) Delete(ctx context.Context, id int64) error {
 	if id < 1 {
 		return ErrRecordNotFound
 	}
@@ -119,17 +238,22 @@ func (m SongModel) Delete(id int64) error {
 		DELETE FROM songs
 		WHERE id = $1`
 
+	ctx, span := startQuerySpan(ctx, "SongModel.Delete")
+	defer span.End()
+
 	//контекст для прерывания запроса который длится дольше 3 секунд
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
 	result, err := m.DB.ExecContext(ctx, query, id)
 	if err != nil {
+		span.RecordError(err)
 		return err
 	}
 
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
+		span.RecordError(err)
 		return err
 	}
 
@@ -140,20 +264,24 @@ func (m SongModel) Delete(id int64) error {
 	return nil
 }
 
-func (m SongModel) Get(id int64) (*Song, error) {
+func (m SongModel) Get(ctx context.Context, id int64) (*Song, error) {
 	if id < 1 {
 		return nil, ErrRecordNotFound
 	}
 
 	query := `
-		SELECT *
+		SELECT id, created_at, "group", name, releaseDate, release_date_precision, text, link, language,
+		rating_sum::float8 / NULLIF(rating_count, 0), rating_count, play_count, version
 		FROM songs
 		WHERE id = $1`
 
 	var song Song
 
+	ctx, span := startQuerySpan(ctx, "SongModel.Get")
+	defer span.End()
+
 	//контекст для прерывания запроса который длится дольше 3 секунд
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 
 	defer cancel()
 
@@ -163,8 +291,13 @@ func (m SongModel) Get(id int64) (*Song, error) {
 		&song.Group,
 		&song.Song,
 		&song.ReleaseDate,
+		&song.ReleaseDate.Precision,
 		&song.Text,
 		&song.Link,
+		&song.Language,
+		&song.AverageRating,
+		&song.RatingCount,
+		&song.PlayCount,
 		&song.Version,
 	)
 
@@ -173,6 +306,7 @@ func (m SongModel) Get(id int64) (*Song, error) {
 		case errors.Is(err, sql.ErrNoRows):
 			return nil, ErrRecordNotFound
 		default:
+			span.RecordError(err)
 			return nil, err
 		}
 	}
@@ -180,32 +314,66 @@ func (m SongModel) Get(id int64) (*Song, error) {
 	return &song, nil
 }
 
-func (m SongModel) Update(song *Song) error {
+func (m SongModel) Update(ctx context.Context, song *Song) error {
+	return updateSong(ctx, m.DB, song)
+}
+
+// обновляет песню и добавляет непустую song.Link к ее ссылкам с типом other от linkAddedBy
+// в одной транзакции. Ссылка, которая у песни уже есть, не считается ошибкой
+func (m SongModel) UpdateWithLink(ctx context.Context, song *Song, linkAddedBy string) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = updateSong(ctx, tx, song)
+	if err != nil {
+		return err
+	}
+
+	if song.Link != "" {
+		err = insertLinkIfMissing(ctx, tx, &Link{SongID: song.ID, Type: "other", URL: song.Link, AddedBy: linkAddedBy})
+		if err != nil {
+			return fmt.Errorf("link: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func updateSong(ctx context.Context, db queryRower, song *Song) error {
 	query := `
 		UPDATE songs
-		SET "group" = $1, name = $2, releaseDate = $3, text=$4,version=version+1
-		WHERE id = $5 AND version = $6
+		SET "group" = $1, name = $2, releaseDate = $3, release_date_precision = $4, text = $5, language = $6, version = version + 1
+		WHERE id = $7 AND version = $8
 		RETURNING version`
 
 	args := []interface{}{
 		song.Group,
 		song.Song,
 		song.ReleaseDate,
+		datePrecision(song.ReleaseDate),
 		song.Text,
+		song.Language,
 		song.ID,
 		song.Version,
 	}
 
+	ctx, span := startQuerySpan(ctx, "SongModel.Update")
+	defer span.End()
+
 	//контекст для прерывания запроса который длится дольше 3 секунд
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
-	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&song.Version)
+	err := db.QueryRowContext(ctx, query, args...).Scan(&song.Version)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
 			return ErrEditConflict
 		default:
+			span.RecordError(err)
 			return err
 		}
 	}
@@ -213,11 +381,14 @@ func (m SongModel) Update(song *Song) error {
 	return nil
 }
 
-func (m SongModel) GetLyricsByID(song *Song, id int64, page int, pageSize int) ([]string, error) {
+func (m SongModel) GetLyricsByID(ctx context.Context, song *Song, id int64, page int, pageSize int) ([]string, error) {
 	query := `SELECT text FROM songs WHERE id = $1`
 
+	ctx, span := startQuerySpan(ctx, "SongModel.GetLyricsByID")
+	defer span.End()
+
 	//контекст для прерывания запроса который длится дольше 3 секунд
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
 	err := m.DB.QueryRowContext(ctx, query, id).Scan(&song.Text)
@@ -225,24 +396,38 @@ func (m SongModel) GetLyricsByID(song *Song, id int64, page int, pageSize int) (
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrRecordNotFound
 		}
+		span.RecordError(err)
 		return nil, err
 	}
 
+	return PaginateVerses(song.Text, page, pageSize), nil
+}
+
+// разбивает текст на куплеты и возвращает куплеты указанной страницы
+func PaginateVerses(text string, page int, pageSize int) []string {
 	// Разбиваем текст на куплеты.
-	verses := strings.Split(song.Text, "\n\n")
+	verses := strings.Split(text, "\n\n")
 
 	// Рассчитываем индексы для пагинации.
 	start := (page - 1) * pageSize
 	end := start + pageSize
 
 	// Проверяем границы.
-	if start >= len(verses) {
-		return nil, nil // Если страница выходит за пределы.
+	if pageSize < 1 || start < 0 || start >= len(verses) {
+		return nil // Если страница выходит за пределы.
 	}
 	if end > len(verses) {
 		end = len(verses)
 	}
 
 	// Возвращаем куплеты для указанной страницы.
-	return verses[start:end], nil
+	return verses[start:end]
+}
+
+// NFC нормализация и единые переводы строк, чтобы куплеты разделялись через "\n\n"
+func normalizeText(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.ReplaceAll(s, "\r", "\n")
+
+	return strings.TrimSpace(norm.NFC.String(s))
 }
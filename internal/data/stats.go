@@ -0,0 +1,93 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type StatsModel struct {
+	DB *sql.DB
+}
+
+// сводка по каталогу для администраторов
+type CatalogStats struct {
+	Songs          int64            `json:"songs"`
+	Groups         int64            `json:"groups"`
+	SongsWithText  int64            `json:"songs_with_text"`
+	Languages      map[string]int64 `json:"languages"`
+	Links          int64            `json:"links"`
+	DeadLinks      int64            `json:"dead_links"`
+	Translations   int64            `json:"translations"`
+	Users          int64            `json:"users"`
+	ActivatedUsers int64            `json:"activated_users"`
+	Plays          int64            `json:"plays"`
+	Favorites      int64            `json:"favorites"`
+	Ratings        int64            `json:"ratings"`
+}
+
+func (m StatsModel) Get(ctx context.Context) (*CatalogStats, error) {
+	query := `
+		SELECT
+		(SELECT count(*) FROM songs),
+		(SELECT count(DISTINCT "group") FROM songs),
+		(SELECT count(*) FROM songs WHERE text <> ''),
+		(SELECT count(*) FROM song_links),
+		(SELECT count(*) FROM song_links WHERE dead),
+		(SELECT count(*) FROM song_translations),
+		(SELECT count(*) FROM users),
+		(SELECT count(*) FROM users WHERE activated),
+		(SELECT count(*) FROM plays),
+		(SELECT count(*) FROM favorites),
+		(SELECT count(*) FROM ratings)`
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	stats := CatalogStats{Languages: make(map[string]int64)}
+
+	err := m.DB.QueryRowContext(ctx, query).Scan(
+		&stats.Songs,
+		&stats.Groups,
+		&stats.SongsWithText,
+		&stats.Links,
+		&stats.DeadLinks,
+		&stats.Translations,
+		&stats.Users,
+		&stats.ActivatedUsers,
+		&stats.Plays,
+		&stats.Favorites,
+		&stats.Ratings,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	//язык не определен - пустая строка
+	rows, err := m.DB.QueryContext(ctx, `SELECT language, count(*) FROM songs GROUP BY language`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var language string
+		var count int64
+
+		err := rows.Scan(&language, &count)
+		if err != nil {
+			return nil, err
+		}
+
+		if language == "" {
+			language = "unknown"
+		}
+		stats.Languages[language] += count
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &stats, nil
+}
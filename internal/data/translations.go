@@ -0,0 +1,228 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Segren/testTask/internal/validator"
+	"golang.org/x/text/language"
+
+	"github.com/Segren/testTask/pkg/musicapi"
+)
+
+type TranslationModel struct {
+	DB *sql.DB
+}
+
+// перевод названия и текста песни на другой язык, общий тип с клиентом
+type Translation = musicapi.Translation
+
+// приводит код языка к канонической форме BCP 47 ("EN-us" -> "en-US")
+func NormalizeLanguage(s string) (string, error) {
+	tag, err := language.Parse(s)
+	if err != nil {
+		return "", err
+	}
+
+	return tag.String(), nil
+}
+
+// проверяет перевод и нормализует его текст так же, как текст оригинала
+func ValidateTranslation(v *validator.Validator, t *Translation) {
+	v.Check(utf8.ValidString(t.Text), "text", "must be valid UTF-8")
+	t.Text = normalizeText(t.Text)
+
+	v.Check(t.Language != "", "language", "must be provided")
+	v.Check(t.Language != "und", "language", "must be a specific language")
+
+	v.Check(t.Title != "", "title", "must be provided")
+	v.Check(len(t.Title) <= 500, "title", "must not be more than 500 bytes long")
+
+	v.Check(t.Text != "", "text", "must be provided")
+	v.Check(len(t.Text) <= maxLyricsBytes, "text", fmt.Sprintf("must not be more than %d bytes long", maxLyricsBytes))
+}
+
+// создает перевод или заменяет существующий на том же языке
+func (m TranslationModel) Upsert(ctx context.Context, t *Translation) error {
+	query := `
+		INSERT INTO song_translations (song_id, language, title, text)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (song_id, language) DO UPDATE
+		SET title = EXCLUDED.title, text = EXCLUDED.text, version = song_translations.version + 1
+		RETURNING created_at, version`
+
+	args := []interface{}{t.SongID, t.Language, t.Title, t.Text}
+
+	ctx, span := startQuerySpan(ctx, "TranslationModel.Upsert")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&t.CreatedAt, &t.Version)
+	span.RecordError(err)
+
+	return err
+}
+
+func (m TranslationModel) Get(ctx context.Context, songID int64, lang string) (*Translation, error) {
+	if songID < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT song_id, language, title, text, created_at, version
+		FROM song_translations
+		WHERE song_id = $1 AND language = $2`
+
+	var t Translation
+
+	ctx, span := startQuerySpan(ctx, "TranslationModel.Get")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, songID, lang).Scan(
+		&t.SongID,
+		&t.Language,
+		&t.Title,
+		&t.Text,
+		&t.CreatedAt,
+		&t.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			span.RecordError(err)
+			return nil, err
+		}
+	}
+
+	return &t, nil
+}
+
+func (m TranslationModel) GetAllForSong(ctx context.Context, songID int64) ([]*Translation, error) {
+	query := `
+		SELECT song_id, language, title, text, created_at, version
+		FROM song_translations
+		WHERE song_id = $1
+		ORDER BY language`
+
+	ctx, span := startQuerySpan(ctx, "TranslationModel.GetAllForSong")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, songID)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	translations := []*Translation{}
+
+	for rows.Next() {
+		var t Translation
+
+		err := rows.Scan(&t.SongID, &t.Language, &t.Title, &t.Text, &t.CreatedAt, &t.Version)
+		if err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+
+		translations = append(translations, &t)
+	}
+
+	if err = rows.Err(); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	return translations, nil
+}
+
+// языки, на которые переведена песня
+func (m TranslationModel) GetLanguages(ctx context.Context, songID int64) ([]string, error) {
+	query := `
+		SELECT language
+		FROM song_translations
+		WHERE song_id = $1
+		ORDER BY language`
+
+	ctx, span := startQuerySpan(ctx, "TranslationModel.GetLanguages")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, songID)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	languages := []string{}
+
+	for rows.Next() {
+		var lang string
+
+		err := rows.Scan(&lang)
+		if err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+
+		languages = append(languages, lang)
+	}
+
+	if err = rows.Err(); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	return languages, nil
+}
+
+func (m TranslationModel) Delete(ctx context.Context, songID int64, lang string) error {
+	if songID < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+		DELETE FROM song_translations
+		WHERE song_id = $1 AND language = $2`
+
+	ctx, span := startQuerySpan(ctx, "TranslationModel.Delete")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, songID, lang)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
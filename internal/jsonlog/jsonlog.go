@@ -2,10 +2,14 @@ package jsonlog
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
+	"maps"
 	"os"
 	"runtime/debug"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -13,7 +17,9 @@ import (
 type Level int8
 
 const (
-	LevelInfo Level = iota
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
 	LevelError
 	LevelFatal
 	LevelOff
@@ -21,34 +27,103 @@ const (
 
 func (l Level) String() string {
 	switch l {
+	case LevelDebug:
+		return "DEBUG"
 	case LevelInfo:
 		return "INFO"
+	case LevelWarn:
+		return "WARN"
 	case LevelError:
 		return "ERROR"
 	case LevelFatal:
 		return "FATAL"
+	case LevelOff:
+		return "OFF"
 	default:
 		return ""
 	}
 }
 
+// уровень по имени без учета регистра: debug, info, warn, error, fatal, off
+func ParseLevel(s string) (Level, error) {
+	for l := LevelDebug; l <= LevelOff; l++ {
+		if strings.EqualFold(s, l.String()) {
+			return l, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown log level %q", s)
+}
+
+// позволяет задавать уровень флагом
+func (l *Level) Set(s string) error {
+	level, err := ParseLevel(s)
+	if err != nil {
+		return err
+	}
+
+	*l = level
+	return nil
+}
+
+// общее состояние логгера и его дочерних логгеров
+type state struct {
+	out         io.Writer
+	mu          sync.Mutex
+	minLevel    atomic.Int32
+	stackTraces atomic.Bool
+}
+
 type Logger struct {
-	out      io.Writer
-	minLevel Level
-	mu       sync.Mutex
+	*state
+	//поля, добавляемые к каждой записи (например, request_id)
+	fields map[string]string
 }
 
 func New(out io.Writer, minLevel Level) *Logger {
-	return &Logger{
-		out:      out,
-		minLevel: minLevel,
+	l := &Logger{state: &state{out: out}}
+	l.SetLevel(minLevel)
+
+	return l
+}
+
+// дочерний логгер с дополнительными полями. Уровень и вывод общие с родителем
+func (l *Logger) With(fields map[string]string) *Logger {
+	merged := maps.Clone(l.fields)
+	if merged == nil {
+		merged = make(map[string]string, len(fields))
 	}
+	maps.Copy(merged, fields)
+
+	return &Logger{state: l.state, fields: merged}
+}
+
+func (l *Logger) Level() Level {
+	return Level(l.minLevel.Load())
+}
+
+// меняет минимальный уровень, в т.ч. для всех дочерних логгеров
+func (l *Logger) SetLevel(level Level) {
+	l.minLevel.Store(int32(level))
+}
+
+// включает трассировку стека в записях уровня ERROR и выше
+func (l *Logger) SetStackTraces(enabled bool) {
+	l.stackTraces.Store(enabled)
+}
+
+func (l *Logger) PrintDebug(message string, properties map[string]string) {
+	l.print(LevelDebug, message, properties)
 }
 
 func (l *Logger) PrintInfo(message string, properties map[string]string) {
 	l.print(LevelInfo, message, properties)
 }
 
+func (l *Logger) PrintWarn(message string, properties map[string]string) {
+	l.print(LevelWarn, message, properties)
+}
+
 func (l *Logger) PrintError(err error, properties map[string]string) {
 	l.print(LevelError, err.Error(), properties)
 }
@@ -59,10 +134,17 @@ func (l *Logger) PrintFatal(err error, properties map[string]string) {
 }
 
 func (l *Logger) print(level Level, message string, properties map[string]string) (int, error) {
-	if level < l.minLevel {
+	if level < l.Level() {
 		return 0, nil
 	}
 
+	//поля дочернего логгера дополняются свойствами записи
+	if len(l.fields) > 0 {
+		merged := maps.Clone(l.fields)
+		maps.Copy(merged, properties)
+		properties = merged
+	}
+
 	aux := struct {
 		Level      string            `json:"level"`
 		Time       string            `json:"time"`
@@ -77,7 +159,7 @@ func (l *Logger) print(level Level, message string, properties map[string]string
 	}
 
 	//трассировка стека
-	if level >= LevelError {
+	if level >= LevelError && l.stackTraces.Load() {
 		aux.Trace = string(debug.Stack())
 	}
 
@@ -0,0 +1,115 @@
+// простое определение языка текста по алфавиту и частым словам
+package langdetect
+
+import (
+	"strings"
+	"unicode"
+)
+
+// язык не определен (BCP 47)
+const Undetermined = "und"
+
+// частые слова языков с латиницей
+var stopwords = map[string][]string{
+	"en": {"the", "and", "you", "to", "of", "in", "is", "it", "my", "me", "that", "with", "for", "your", "on"},
+	"es": {"el", "la", "que", "de", "y", "en", "los", "mi", "tu", "por", "con", "una", "las", "es", "no"},
+	"fr": {"le", "la", "les", "et", "je", "tu", "de", "des", "est", "pas", "que", "une", "mon", "dans", "pour"},
+	"de": {"der", "die", "und", "ich", "du", "das", "nicht", "ist", "ein", "mich", "mit", "dich", "zu", "auf", "wir"},
+	"it": {"il", "che", "di", "e", "la", "non", "per", "un", "sono", "mi", "ti", "con", "della", "ma", "io"},
+	"pt": {"o", "que", "de", "e", "não", "eu", "um", "uma", "você", "com", "meu", "por", "os", "se", "para"},
+}
+
+// возвращает код языка BCP 47 или "und" если язык не удалось определить
+func Detect(text string) string {
+	counts := make(map[string]int)
+	total := 0
+
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		total++
+
+		switch {
+		case unicode.Is(unicode.Cyrillic, r):
+			counts["cyrillic"]++
+			if strings.ContainsRune("іїєґІЇЄҐ", r) {
+				counts["uk"]++
+			}
+		case unicode.Is(unicode.Latin, r):
+			counts["latin"]++
+		case unicode.Is(unicode.Greek, r):
+			counts["el"]++
+		case unicode.Is(unicode.Hiragana, r), unicode.Is(unicode.Katakana, r):
+			counts["ja"]++
+		case unicode.Is(unicode.Hangul, r):
+			counts["ko"]++
+		case unicode.Is(unicode.Han, r):
+			counts["zh"]++
+		case unicode.Is(unicode.Arabic, r):
+			counts["ar"]++
+		case unicode.Is(unicode.Hebrew, r):
+			counts["he"]++
+		}
+	}
+
+	if total == 0 {
+		return Undetermined
+	}
+
+	//японский текст содержит и кану, и иероглифы
+	if counts["ja"] > 0 && counts["ja"]+counts["zh"] > total/2 {
+		return "ja"
+	}
+
+	script, best := "", 0
+	for k, n := range counts {
+		if k != "uk" && n > best {
+			script, best = k, n
+		}
+	}
+
+	if best*2 < total {
+		return Undetermined
+	}
+
+	switch script {
+	case "cyrillic":
+		if counts["uk"] > 0 {
+			return "uk"
+		}
+		return "ru"
+	case "latin":
+		return detectLatin(text)
+	default:
+		return script
+	}
+}
+
+// выбирает язык с латиницей по количеству частых слов
+func detectLatin(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+
+	scores := make(map[string]int)
+	for _, word := range words {
+		for lang, list := range stopwords {
+			for _, stopword := range list {
+				if word == stopword {
+					scores[lang]++
+					break
+				}
+			}
+		}
+	}
+
+	lang, best := Undetermined, 0
+	for _, l := range []string{"en", "es", "fr", "de", "it", "pt"} {
+		if scores[l] > best {
+			lang, best = l, scores[l]
+		}
+	}
+
+	return lang
+}
@@ -0,0 +1,35 @@
+package langdetect
+
+import "testing"
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"empty", "", Undetermined},
+		{"no letters", "123, 456!\n", Undetermined},
+		{"russian", "Я свободен, словно птица в небесах", "ru"},
+		{"ukrainian letters", "Ніч яка місячна, зоряна, ясная", "uk"},
+		{"english", "I want to break free from your lies", "en"},
+		{"spanish", "La vida es una canción que no termina", "es"},
+		{"german", "Ich will dich und du bist nicht hier", "de"},
+		{"latin without stopwords", "Bohemian Rhapsody", Undetermined},
+		{"mostly cyrillic", "Группа крови на рукаве, my baby", "ru"},
+		{"mostly latin", "Hello and goodbye to you, привет", "en"},
+		//ни один алфавит не набирает половины букв
+		{"evenly mixed", "abcd αβγδ абвг", Undetermined},
+		{"greek", "Σαγαπώ", "el"},
+		{"japanese kana and kanji", "君の名は、さよなら", "ja"},
+		{"chinese", "月亮代表我的心", "zh"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Detect(tt.text); got != tt.want {
+				t.Errorf("Detect(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package mailer
+
+import (
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Segren/testTask/internal/jsonlog"
+)
+
+// отправка через smtp сервер
+type SMTPSender struct {
+	addr string
+	auth smtp.Auth
+}
+
+func NewSMTPSender(host string, port int, username, password string) *SMTPSender {
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return &SMTPSender{
+		addr: net.JoinHostPort(host, strconv.Itoa(port)),
+		auth: auth,
+	}
+}
+
+func (s *SMTPSender) Send(msg *Message) error {
+	return smtp.SendMail(s.addr, s.auth, msg.From, []string{msg.To}, msg.Bytes())
+}
+
+// для локальной разработки: каждое письмо сохраняется в отдельный .eml файл
+type FileSender struct {
+	dir string
+	mu  sync.Mutex
+	seq int
+}
+
+func NewFileSender(dir string) (*FileSender, error) {
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileSender{dir: dir}, nil
+}
+
+func (s *FileSender) Send(msg *Message) error {
+	s.mu.Lock()
+	s.seq++
+	name := fmt.Sprintf("%s-%d.eml", time.Now().UTC().Format("20060102T150405"), s.seq)
+	s.mu.Unlock()
+
+	return os.WriteFile(filepath.Join(s.dir, name), msg.Bytes(), 0o644)
+}
+
+// для локальной разработки: письма пишутся в лог приложения
+type LogSender struct {
+	logger *jsonlog.Logger
+}
+
+func NewLogSender(logger *jsonlog.Logger) *LogSender {
+	return &LogSender{logger: logger}
+}
+
+func (s *LogSender) Send(msg *Message) error {
+	s.logger.PrintInfo("email sent", map[string]string{
+		"from":    msg.From,
+		"to":      msg.To,
+		"subject": msg.Subject,
+		"body":    msg.PlainBody,
+	})
+
+	return nil
+}
+
+// письмо в формате MIME multipart/alternative
+func (msg *Message) Bytes() []byte {
+	const boundary = "music-info-boundary"
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "From: %s\r\n", msg.From)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&b, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", boundary)
+
+	fmt.Fprintf(&b, "--%s\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s\r\n", boundary, msg.PlainBody)
+	fmt.Fprintf(&b, "--%s\r\nContent-Type: text/html; charset=UTF-8\r\n\r\n%s\r\n", boundary, msg.HTMLBody)
+	fmt.Fprintf(&b, "--%s--\r\n", boundary)
+
+	return []byte(b.String())
+}
@@ -0,0 +1,380 @@
+// метрики приложения: счетчики, датчики и гистограммы с выводом в expvar
+// и в текстовом формате Prometheus
+package metrics
+
+import (
+	"expvar"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// границы гистограммы длительности запросов в секундах
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// набор метрик. Метрики выводятся в порядке регистрации
+type Registry struct {
+	mu       sync.Mutex
+	families []*family
+}
+
+type family struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+	metric collector
+}
+
+type collector interface {
+	//значения метрики: ключ - значения меток через \xff
+	samples() map[string]any
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) register(name, help, kind string, labels []string, metric collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.families = append(r.families, &family{name: name, help: help, kind: kind, labels: labels, metric: metric})
+}
+
+// монотонно растущий счетчик
+type Counter struct {
+	v atomic.Int64
+}
+
+func (c *Counter) Inc() {
+	c.v.Add(1)
+}
+
+func (c *Counter) Add(n int64) {
+	c.v.Add(n)
+}
+
+func (c *Counter) samples() map[string]any {
+	return map[string]any{"": c.v.Load()}
+}
+
+func (r *Registry) Counter(name, help string) *Counter {
+	c := &Counter{}
+	r.register(name, help, "counter", nil, c)
+	return c
+}
+
+// значение, которое может расти и уменьшаться
+type Gauge struct {
+	v atomic.Int64
+}
+
+func (g *Gauge) Inc() {
+	g.v.Add(1)
+}
+
+func (g *Gauge) Dec() {
+	g.v.Add(-1)
+}
+
+func (g *Gauge) samples() map[string]any {
+	return map[string]any{"": g.v.Load()}
+}
+
+func (r *Registry) Gauge(name, help string) *Gauge {
+	g := &Gauge{}
+	r.register(name, help, "gauge", nil, g)
+	return g
+}
+
+type funcMetric func() float64
+
+func (f funcMetric) samples() map[string]any {
+	return map[string]any{"": f()}
+}
+
+// датчик, значение которого вычисляется при чтении метрик
+func (r *Registry) GaugeFunc(name, help string, fn func() float64) {
+	r.register(name, help, "gauge", nil, funcMetric(fn))
+}
+
+// счетчик, значение которого вычисляется при чтении метрик
+func (r *Registry) CounterFunc(name, help string, fn func() float64) {
+	r.register(name, help, "counter", nil, funcMetric(fn))
+}
+
+// счетчики с метками
+type CounterVec struct {
+	mu     sync.Mutex
+	values map[string]*Counter
+}
+
+func (v *CounterVec) WithLabelValues(values ...string) *Counter {
+	key := strings.Join(values, "\xff")
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	c, ok := v.values[key]
+	if !ok {
+		c = &Counter{}
+		v.values[key] = c
+	}
+
+	return c
+}
+
+func (v *CounterVec) samples() map[string]any {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	samples := make(map[string]any, len(v.values))
+	for key, c := range v.values {
+		samples[key] = c.v.Load()
+	}
+
+	return samples
+}
+
+func (r *Registry) CounterVec(name, help string, labels ...string) *CounterVec {
+	v := &CounterVec{values: make(map[string]*Counter)}
+	r.register(name, help, "counter", labels, v)
+	return v
+}
+
+// гистограмма с фиксированными границами
+type Histogram struct {
+	mu      sync.Mutex
+	buckets []float64
+	counts  []uint64
+	count   uint64
+	sum     float64
+}
+
+func (h *Histogram) Observe(value float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i, bound := range h.buckets {
+		if value <= bound {
+			h.counts[i]++
+		}
+	}
+
+	h.count++
+	h.sum += value
+}
+
+// снимок гистограммы для вывода, counts накопительные
+type histogramSnapshot struct {
+	Buckets map[string]uint64 `json:"buckets"`
+	Count   uint64            `json:"count"`
+	Sum     float64           `json:"sum"`
+
+	bounds []float64
+	counts []uint64
+}
+
+func (h *Histogram) snapshot() histogramSnapshot {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := histogramSnapshot{
+		Buckets: make(map[string]uint64, len(h.buckets)),
+		Count:   h.count,
+		Sum:     h.sum,
+		bounds:  h.buckets,
+		counts:  slices.Clone(h.counts),
+	}
+
+	for i, bound := range h.buckets {
+		s.Buckets[formatFloat(bound)] = h.counts[i]
+	}
+
+	return s
+}
+
+// гистограммы с метками
+type HistogramVec struct {
+	mu      sync.Mutex
+	buckets []float64
+	values  map[string]*Histogram
+}
+
+func (v *HistogramVec) WithLabelValues(values ...string) *Histogram {
+	key := strings.Join(values, "\xff")
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	h, ok := v.values[key]
+	if !ok {
+		h = &Histogram{buckets: v.buckets, counts: make([]uint64, len(v.buckets))}
+		v.values[key] = h
+	}
+
+	return h
+}
+
+func (v *HistogramVec) samples() map[string]any {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	samples := make(map[string]any, len(v.values))
+	for key, h := range v.values {
+		samples[key] = h.snapshot()
+	}
+
+	return samples
+}
+
+func (r *Registry) HistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	v := &HistogramVec{buckets: buckets, values: make(map[string]*Histogram)}
+	r.register(name, help, "histogram", labels, v)
+	return v
+}
+
+// публикует каждую метрику в expvar под ее именем
+func (r *Registry) PublishExpvar() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, f := range r.families {
+		expvar.Publish(f.name, expvar.Func(f.expvarValue))
+	}
+}
+
+// значение для expvar: число без меток или объект "метка=значение,..." -> значение
+func (f *family) expvarValue() any {
+	samples := f.metric.samples()
+
+	if len(f.labels) == 0 {
+		return samples[""]
+	}
+
+	values := make(map[string]any, len(samples))
+	for key, value := range samples {
+		var pairs []string
+		for i, labelValue := range strings.Split(key, "\xff") {
+			pairs = append(pairs, f.labels[i]+"="+labelValue)
+		}
+		values[strings.Join(pairs, ",")] = value
+	}
+
+	return values
+}
+
+// выводит метрики в текстовом формате Prometheus 0.0.4
+func (r *Registry) WritePrometheus(w io.Writer) error {
+	r.mu.Lock()
+	families := slices.Clone(r.families)
+	r.mu.Unlock()
+
+	for _, f := range families {
+		_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.name, escapeHelp(f.help), f.name, f.kind)
+		if err != nil {
+			return err
+		}
+
+		samples := f.metric.samples()
+
+		keys := make([]string, 0, len(samples))
+		for key := range samples {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+
+		for _, key := range keys {
+			labels := f.labelPairs(key)
+
+			switch value := samples[key].(type) {
+			case histogramSnapshot:
+				err = writeHistogram(w, f.name, labels, value)
+			default:
+				_, err = fmt.Fprintf(w, "%s%s %v\n", f.name, formatLabels(labels), formatValue(value))
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func writeHistogram(w io.Writer, name string, labels []string, h histogramSnapshot) error {
+	for i, bound := range h.bounds {
+		_, err := fmt.Fprintf(w, "%s_bucket%s %d\n", name, formatLabels(append(labels, `le="`+formatFloat(bound)+`"`)), h.counts[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprintf(w, "%s_bucket%s %d\n%s_sum%s %s\n%s_count%s %d\n",
+		name, formatLabels(append(labels, `le="+Inf"`)), h.Count,
+		name, formatLabels(labels), formatFloat(h.Sum),
+		name, formatLabels(labels), h.Count,
+	)
+	return err
+}
+
+// пары метка="значение" для ключа значения
+func (f *family) labelPairs(key string) []string {
+	if len(f.labels) == 0 {
+		return nil
+	}
+
+	var pairs []string
+	for i, value := range strings.Split(key, "\xff") {
+		pairs = append(pairs, f.labels[i]+`="`+escapeLabel(value)+`"`)
+	}
+
+	return pairs
+}
+
+func formatLabels(pairs []string) string {
+	if len(pairs) == 0 {
+		return ""
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatValue(value any) string {
+	switch v := value.(type) {
+	case float64:
+		return formatFloat(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+}
+
+var (
+	labelReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpReplacer  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabel(s string) string {
+	return labelReplacer.Replace(s)
+}
+
+func escapeHelp(s string) string {
+	return helpReplacer.Replace(s)
+}
@@ -0,0 +1,338 @@
+// применение sql миграций из встроенных файлов. Версия схемы хранится в таблице
+// schema_migrations в том же формате, что у утилиты golang-migrate, поэтому базы,
+// размеченные ею раньше, продолжают работать
+package migrate
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrDirty      = errors.New("schema is dirty: a previous migration failed; fix the schema by hand, then set schema_migrations.dirty to false")
+	ErrNoVersion  = errors.New("migration version does not exist")
+	ErrNoChange   = errors.New("no change")
+	ErrNoDownFile = errors.New("missing down migration")
+)
+
+// ключ pg_advisory_lock, общий для всех экземпляров api
+const lockKey int64 = 0x6d75736963 // "music"
+
+var fileRX = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version int64
+	Name    string
+	up      string
+	down    string
+	hasDown bool
+}
+
+// загружает миграции из fsys, отсортированные по версии
+func Load(fsys fs.FS) ([]*Migration, error) {
+	files, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+
+	for _, file := range files {
+		m := fileRX.FindStringSubmatch(path.Base(file))
+		if m == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", file)
+		}
+
+		version, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration file name %q", file)
+		}
+
+		content, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = migration
+		}
+
+		if migration.Name != m[2] {
+			return nil, fmt.Errorf("migration %d has different names: %q and %q", version, migration.Name, m[2])
+		}
+
+		if m[3] == "up" {
+			migration.up = string(content)
+		} else {
+			migration.down = string(content)
+			migration.hasDown = true
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		migrations = append(migrations, migration)
+	}
+
+	slices.SortFunc(migrations, func(a, b *Migration) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+
+	return migrations, nil
+}
+
+type Migrator struct {
+	DB         *sql.DB
+	Migrations []*Migration
+	//вызывается после каждой примененной миграции
+	Log func(direction string, m *Migration)
+}
+
+func New(db *sql.DB, fsys fs.FS) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{DB: db, Migrations: migrations}, nil
+}
+
+// версия последней миграции, с которой работает код
+func (m *Migrator) Latest() int64 {
+	if len(m.Migrations) == 0 {
+		return 0
+	}
+	return m.Migrations[len(m.Migrations)-1].Version
+}
+
+// состояние миграции для status
+type State struct {
+	Migration *Migration
+	Applied   bool
+}
+
+// текущая версия схемы, признак dirty и список миграций с отметкой о применении
+func (m *Migrator) Status(ctx context.Context) (int64, bool, []State, error) {
+	version, dirty, err := m.version(ctx, m.DB)
+	if err != nil {
+		return 0, false, nil, err
+	}
+
+	states := make([]State, 0, len(m.Migrations))
+	for _, migration := range m.Migrations {
+		states = append(states, State{Migration: migration, Applied: migration.Version <= version})
+	}
+
+	return version, dirty, states, nil
+}
+
+// применяет все непримененные миграции
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.Goto(ctx, m.Latest())
+}
+
+// откатывает steps последних миграций
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	return m.withLock(ctx, func(conn *sql.Conn, current int64) error {
+		idx := m.index(current)
+		if current != 0 && idx < 0 {
+			return fmt.Errorf("current version %d: %w", current, ErrNoVersion)
+		}
+
+		if idx < 0 {
+			return ErrNoChange
+		}
+
+		target := int64(0)
+		if idx-steps >= 0 {
+			target = m.Migrations[idx-steps].Version
+		}
+
+		return m.migrate(ctx, conn, current, target)
+	})
+}
+
+// применяет или откатывает миграции до версии target. 0 - откатить все
+func (m *Migrator) Goto(ctx context.Context, target int64) error {
+	if target != 0 && m.index(target) < 0 {
+		return fmt.Errorf("version %d: %w", target, ErrNoVersion)
+	}
+
+	return m.withLock(ctx, func(conn *sql.Conn, current int64) error {
+		return m.migrate(ctx, conn, current, target)
+	})
+}
+
+func (m *Migrator) migrate(ctx context.Context, conn *sql.Conn, current, target int64) error {
+	if current == target {
+		return ErrNoChange
+	}
+
+	if current < target {
+		for _, migration := range m.Migrations {
+			if migration.Version <= current || migration.Version > target {
+				continue
+			}
+
+			err := m.apply(ctx, conn, migration.Version, migration.up)
+			if err != nil {
+				return fmt.Errorf("migration %d_%s up: %w", migration.Version, migration.Name, err)
+			}
+			m.log("up", migration)
+		}
+
+		return nil
+	}
+
+	for i := len(m.Migrations) - 1; i >= 0; i-- {
+		migration := m.Migrations[i]
+		if migration.Version > current || migration.Version <= target {
+			continue
+		}
+
+		if !migration.hasDown {
+			return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, ErrNoDownFile)
+		}
+
+		//после отката версией становится предыдущая миграция
+		previous := int64(0)
+		if i > 0 {
+			previous = m.Migrations[i-1].Version
+		}
+
+		err := m.apply(ctx, conn, previous, migration.down)
+		if err != nil {
+			return fmt.Errorf("migration %d_%s down: %w", migration.Version, migration.Name, err)
+		}
+		m.log("down", migration)
+	}
+
+	return nil
+}
+
+// выполняет sql миграции. На время выполнения схема помечается dirty с новой версией,
+// чтобы упавшая миграция не осталась незамеченной
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, version int64, query string) error {
+	err := m.setVersion(ctx, conn, version, true)
+	if err != nil {
+		return err
+	}
+
+	_, err = conn.ExecContext(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	return m.setVersion(ctx, conn, version, false)
+}
+
+// выполняет fn под advisory lock, чтобы несколько экземпляров не применяли миграции одновременно.
+// Блокировка сессионная, поэтому все запросы идут через одно подключение
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn, current int64) error) error {
+	conn, err := m.DB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockKey)
+	if err != nil {
+		return fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockKey)
+
+	_, err = conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (version bigint NOT NULL PRIMARY KEY, dirty boolean NOT NULL)`)
+	if err != nil {
+		return err
+	}
+
+	//версия читается уже под блокировкой: другой экземпляр мог только что применить миграции
+	current, dirty, err := m.version(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	if dirty {
+		return fmt.Errorf("version %d: %w", current, ErrDirty)
+	}
+
+	return fn(conn, current)
+}
+
+type querier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func (m *Migrator) version(ctx context.Context, q querier) (int64, bool, error) {
+	var version int64
+	var dirty bool
+
+	err := q.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, false, nil
+		case isUndefinedTable(err):
+			//миграции еще ни разу не запускались
+			return 0, false, nil
+		default:
+			return 0, false, err
+		}
+	}
+
+	return version, dirty, nil
+}
+
+func (m *Migrator) setVersion(ctx context.Context, conn *sql.Conn, version int64, dirty bool) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `TRUNCATE schema_migrations`)
+	if err != nil {
+		return err
+	}
+
+	//версия 0 означает пустую схему и не хранится
+	if version > 0 {
+		_, err = tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, dirty) VALUES ($1, $2)`, version, dirty)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// индекс миграции с версией version или -1
+func (m *Migrator) index(version int64) int {
+	return slices.IndexFunc(m.Migrations, func(migration *Migration) bool {
+		return migration.Version == version
+	})
+}
+
+func (m *Migrator) log(direction string, migration *Migration) {
+	if m.Log != nil {
+		m.Log(direction, migration)
+	}
+}
+
+// таблицы не существует
+func isUndefinedTable(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "42P01"
+}
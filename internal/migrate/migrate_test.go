@@ -0,0 +1,276 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func file(content string) *fstest.MapFile {
+	return &fstest.MapFile{Data: []byte(content)}
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name     string
+		fsys     fstest.MapFS
+		versions []int64
+		hasDown  []bool
+		wantErr  string
+	}{
+		{
+			name: "sorted numerically",
+			fsys: fstest.MapFS{
+				"10_ten.up.sql":     file("SELECT 10"),
+				"2_two.up.sql":      file("SELECT 2"),
+				"2_two.down.sql":    file("SELECT -2"),
+				"000001_one.up.sql": file("SELECT 1"),
+			},
+			versions: []int64{1, 2, 10},
+			hasDown:  []bool{false, true, false},
+		},
+		{
+			name: "only sql files",
+			fsys: fstest.MapFS{
+				"1_one.up.sql":  file("SELECT 1"),
+				"migrations.go": file("package migrations"),
+			},
+			versions: []int64{1},
+			hasDown:  []bool{false},
+		},
+		{
+			name:     "empty",
+			fsys:     fstest.MapFS{},
+			versions: []int64{},
+			hasDown:  []bool{},
+		},
+		{
+			name:    "invalid name",
+			fsys:    fstest.MapFS{"one.up.sql": file("SELECT 1")},
+			wantErr: `invalid migration file name "one.up.sql"`,
+		},
+		{
+			name:    "unknown direction",
+			fsys:    fstest.MapFS{"1_one.sideways.sql": file("SELECT 1")},
+			wantErr: "invalid migration file name",
+		},
+		{
+			name: "different names for one version",
+			fsys: fstest.MapFS{
+				"1_one.up.sql":   file("SELECT 1"),
+				"1_uno.down.sql": file("SELECT -1"),
+			},
+			wantErr: "migration 1 has different names",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrations, err := Load(tt.fsys)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(migrations) != len(tt.versions) {
+				t.Fatalf("got %d migrations, want %d", len(migrations), len(tt.versions))
+			}
+			for i, m := range migrations {
+				if m.Version != tt.versions[i] || m.hasDown != tt.hasDown[i] {
+					t.Errorf("migration %d: got version %d, down %t; want %d, %t", i, m.Version, m.hasDown, tt.versions[i], tt.hasDown[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLoadReadsContent(t *testing.T) {
+	migrations, err := Load(fstest.MapFS{
+		"1_create.up.sql":   file("CREATE TABLE t (id int)"),
+		"1_create.down.sql": file("DROP TABLE t"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := migrations[0]
+	if m.Name != "create" || m.up != "CREATE TABLE t (id int)" || m.down != "DROP TABLE t" {
+		t.Errorf("got %+v", m)
+	}
+}
+
+// подключение к отдельной схеме тестовой бд из MUSIC_TEST_DB_DSN. Без переменной тест пропускается
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dsn := os.Getenv("MUSIC_TEST_DB_DSN")
+	if dsn == "" {
+		t.Skip("MUSIC_TEST_DB_DSN is not set")
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schema := fmt.Sprintf("migrate_test_%d", time.Now().UnixNano())
+	_, err = db.Exec(`CREATE SCHEMA ` + schema)
+	if err != nil {
+		db.Close()
+		t.Fatal(err)
+	}
+	db.Close()
+
+	t.Cleanup(func() {
+		db, err := sql.Open("postgres", dsn)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer db.Close()
+
+		_, err = db.Exec(`DROP SCHEMA ` + schema + ` CASCADE`)
+		if err != nil {
+			t.Error(err)
+		}
+	})
+
+	//lib/pq передает неизвестные параметры подключения серверу, так все таблицы теста попадают в схему
+	if strings.Contains(dsn, "://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		q := u.Query()
+		q.Set("search_path", schema)
+		u.RawQuery = q.Encode()
+		dsn = u.String()
+	} else {
+		dsn += " search_path=" + schema
+	}
+
+	db, err = sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+func tableExists(t *testing.T, db *sql.DB, name string) bool {
+	t.Helper()
+
+	var exists bool
+	err := db.QueryRow(`SELECT to_regclass($1) IS NOT NULL`, name).Scan(&exists)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return exists
+}
+
+func TestMigratorDownAndDirty(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+
+	migrator, err := New(db, fstest.MapFS{
+		"1_first.up.sql":    file("CREATE TABLE first (id int)"),
+		"1_first.down.sql":  file("DROP TABLE first"),
+		"2_second.up.sql":   file("CREATE TABLE second (id int)"),
+		"2_second.down.sql": file("DROP TABLE second"),
+		"3_broken.up.sql":   file("CREATE TABLE third (id int"),
+		"3_broken.down.sql": file("DROP TABLE third"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = migrator.Goto(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tableExists(t, db, "second") {
+		t.Fatal("table second was not created")
+	}
+
+	err = migrator.Goto(ctx, 2)
+	if !errors.Is(err, ErrNoChange) {
+		t.Fatalf("repeated goto: got %v, want ErrNoChange", err)
+	}
+
+	err = migrator.Down(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tableExists(t, db, "second") || !tableExists(t, db, "first") {
+		t.Fatal("down 1 must drop only the last migration")
+	}
+
+	version, dirty, _, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != 1 || dirty {
+		t.Fatalf("after down: got version %d dirty %t, want 1 false", version, dirty)
+	}
+
+	//упавшая миграция оставляет схему dirty со своей версией
+	err = migrator.Up(ctx)
+	if err == nil {
+		t.Fatal("broken migration was applied")
+	}
+
+	version, dirty, _, err = migrator.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != 3 || !dirty {
+		t.Fatalf("after failed up: got version %d dirty %t, want 3 true", version, dirty)
+	}
+
+	for name, run := range map[string]func() error{
+		"up":   func() error { return migrator.Up(ctx) },
+		"down": func() error { return migrator.Down(ctx, 1) },
+		"goto": func() error { return migrator.Goto(ctx, 1) },
+	} {
+		err = run()
+		if !errors.Is(err, ErrDirty) {
+			t.Errorf("%s on dirty schema: got %v, want ErrDirty", name, err)
+		}
+	}
+
+	//после ручного исправления откат снова работает
+	_, err = db.Exec(`UPDATE schema_migrations SET version = 2, dirty = false`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = migrator.Down(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tableExists(t, db, "first") || tableExists(t, db, "second") {
+		t.Fatal("down 2 must drop both tables")
+	}
+
+	version, _, _, err = migrator.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != 0 {
+		t.Fatalf("after full down: got version %d, want 0", version)
+	}
+}
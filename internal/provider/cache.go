@@ -0,0 +1,260 @@
+package provider
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Segren/testTask/internal/data"
+)
+
+// постоянный уровень кеша (например, таблица provider_cache в postgres)
+type Store interface {
+	Get(key string) (*data.ProviderCacheEntry, error)
+	Set(entry *data.ProviderCacheEntry) error
+}
+
+// статистика кеша для эндпоинта метрик
+type Stats struct {
+	Hits         int64 `json:"hits"`
+	NegativeHits int64 `json:"negative_hits"`
+	Misses       int64 `json:"misses"`
+	StoreHits    int64 `json:"store_hits"`
+	StoreErrors  int64 `json:"store_errors"`
+	Evictions    int64 `json:"evictions"`
+	Entries      int   `json:"entries"`
+}
+
+type cacheItem struct {
+	key       string
+	detail    *data.SongDetail
+	notFound  bool
+	expiresAt time.Time
+}
+
+// LRU кеш с TTL перед Fetcher. Ответы 404 кешируются отдельно на negativeTTL.
+type Cache struct {
+	next        Fetcher
+	store       Store
+	size        int
+	ttl         time.Duration
+	negativeTTL time.Duration
+
+	//источник данных (адрес внешнего api), входит в ключи постоянного уровня
+	scope atomic.Pointer[string]
+
+	//вызывается при ошибках постоянного уровня, запрос при этом обслуживается как обычно
+	onStoreError func(error)
+
+	mu    sync.Mutex
+	ll    *list.List
+	items map[string]*list.Element
+
+	hits         atomic.Int64
+	negativeHits atomic.Int64
+	misses       atomic.Int64
+	storeHits    atomic.Int64
+	storeErrors  atomic.Int64
+	evictions    atomic.Int64
+}
+
+// store может быть nil, тогда используется только память
+func NewCache(next Fetcher, store Store, size int, ttl, negativeTTL time.Duration) *Cache {
+	return &Cache{
+		next:        next,
+		store:       store,
+		size:        size,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		ll:          list.New(),
+		items:       make(map[string]*list.Element),
+	}
+}
+
+// задает обработчик ошибок постоянного уровня, вызывать до начала работы кеша
+func (c *Cache) OnStoreError(fn func(error)) {
+	c.onStoreError = fn
+}
+
+// ключ кеша не зависит от регистра и лишних пробелов
+func Key(group, song string) string {
+	normalize := func(s string) string {
+		return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+	}
+
+	return normalize(group) + "\x00" + normalize(song)
+}
+
+// меняет источник данных: записи в памяти сбрасываются, а записи постоянного уровня
+// от прежнего источника перестают находиться, потому что scope входит в их ключ
+func (c *Cache) SetScope(scope string) {
+	c.scope.Store(&scope)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.ll.Init()
+	clear(c.items)
+}
+
+// ключ записи с учетом источника данных
+func (c *Cache) key(group, song string) string {
+	key := Key(group, song)
+	if scope := c.scope.Load(); scope != nil && *scope != "" {
+		key = *scope + "\x00" + key
+	}
+
+	return key
+}
+
+func (c *Cache) Fetch(ctx context.Context, group, song string) (*data.SongDetail, error) {
+	key := c.key(group, song)
+
+	if item, ok := c.get(key); ok {
+		if item.notFound {
+			c.negativeHits.Add(1)
+			return nil, ErrNotFound
+		}
+		c.hits.Add(1)
+		return copyDetail(item.detail), nil
+	}
+
+	if c.store != nil {
+		entry, err := c.store.Get(key)
+		switch {
+		case err == nil:
+			c.storeHits.Add(1)
+			c.add(&cacheItem{key: key, detail: entry.Detail, notFound: entry.NotFound, expiresAt: entry.ExpiresAt})
+			if entry.NotFound {
+				return nil, ErrNotFound
+			}
+			return copyDetail(entry.Detail), nil
+		case !errors.Is(err, data.ErrRecordNotFound):
+			c.storeError(fmt.Errorf("provider cache get: %w", err))
+		}
+	}
+
+	c.misses.Add(1)
+
+	detail, err := c.next.Fetch(ctx, group, song)
+	switch {
+	case err == nil:
+		c.save(&cacheItem{key: key, detail: copyDetail(detail), expiresAt: time.Now().Add(c.ttl)})
+		return detail, nil
+	case errors.Is(err, ErrNotFound):
+		c.save(&cacheItem{key: key, notFound: true, expiresAt: time.Now().Add(c.negativeTTL)})
+		return nil, err
+	default:
+		return nil, err
+	}
+}
+
+// проверяет доступность источника за кешем
+func (c *Cache) Ping(ctx context.Context) error {
+	if p, ok := c.next.(Pinger); ok {
+		return p.Ping(ctx)
+	}
+
+	return nil
+}
+
+func (c *Cache) Stats() Stats {
+	c.mu.Lock()
+	entries := c.ll.Len()
+	c.mu.Unlock()
+
+	return Stats{
+		Hits:         c.hits.Load(),
+		NegativeHits: c.negativeHits.Load(),
+		Misses:       c.misses.Load(),
+		StoreHits:    c.storeHits.Load(),
+		StoreErrors:  c.storeErrors.Load(),
+		Evictions:    c.evictions.Load(),
+		Entries:      entries,
+	}
+}
+
+func (c *Cache) get(key string) (*cacheItem, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+
+	item := el.Value.(*cacheItem)
+	if time.Now().After(item.expiresAt) {
+		c.ll.Remove(el)
+		delete(c.items, key)
+		return nil, false
+	}
+
+	c.ll.MoveToFront(el)
+	return item, true
+}
+
+func (c *Cache) add(item *cacheItem) {
+	if c.size <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[item.key]; ok {
+		el.Value = item
+		c.ll.MoveToFront(el)
+		return
+	}
+
+	c.items[item.key] = c.ll.PushFront(item)
+
+	//вытесняем самые давно использованные записи
+	for c.ll.Len() > c.size {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*cacheItem).key)
+		c.evictions.Add(1)
+	}
+}
+
+// сохраняет запись в памяти и в постоянном уровне
+func (c *Cache) save(item *cacheItem) {
+	c.add(item)
+
+	if c.store == nil {
+		return
+	}
+
+	err := c.store.Set(&data.ProviderCacheEntry{
+		Key:       item.key,
+		Detail:    item.detail,
+		NotFound:  item.notFound,
+		ExpiresAt: item.expiresAt,
+	})
+	if err != nil {
+		c.storeError(fmt.Errorf("provider cache set: %w", err))
+	}
+}
+
+func (c *Cache) storeError(err error) {
+	c.storeErrors.Add(1)
+
+	if c.onStoreError != nil {
+		c.onStoreError(err)
+	}
+}
+
+func copyDetail(detail *data.SongDetail) *data.SongDetail {
+	if detail == nil {
+		return nil
+	}
+	d := *detail
+	return &d
+}
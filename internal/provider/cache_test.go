@@ -0,0 +1,113 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Segren/testTask/internal/data"
+)
+
+// источник, который отвечает своим адресом в поле Link
+type urlFetcher struct {
+	url   string
+	calls int
+}
+
+func (f *urlFetcher) Fetch(ctx context.Context, group, song string) (*data.SongDetail, error) {
+	f.calls++
+	return &data.SongDetail{Link: f.url}, nil
+}
+
+type mapStore map[string]*data.ProviderCacheEntry
+
+func (s mapStore) Get(key string) (*data.ProviderCacheEntry, error) {
+	entry, ok := s[key]
+	if !ok {
+		return nil, data.ErrRecordNotFound
+	}
+
+	return entry, nil
+}
+
+func (s mapStore) Set(entry *data.ProviderCacheEntry) error {
+	s[entry.Key] = entry
+	return nil
+}
+
+// постоянный уровень, который всегда отвечает ошибкой
+type brokenStore struct{}
+
+func (brokenStore) Get(key string) (*data.ProviderCacheEntry, error) {
+	return nil, errors.New("connection refused")
+}
+
+func (brokenStore) Set(entry *data.ProviderCacheEntry) error {
+	return errors.New("connection refused")
+}
+
+func TestCacheSetScopeDropsPreviousSource(t *testing.T) {
+	fetcher := &urlFetcher{url: "http://old.example/info"}
+	store := mapStore{}
+
+	cache := NewCache(fetcher, store, 10, time.Hour, time.Minute)
+	cache.SetScope(fetcher.url)
+
+	for range 2 {
+		detail, err := cache.Fetch(context.Background(), "Muse", "Uprising")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if detail.Link != "http://old.example/info" {
+			t.Fatalf("got link %q from the old source", detail.Link)
+		}
+	}
+	if fetcher.calls != 1 {
+		t.Fatalf("old source: got %d fetches, want 1", fetcher.calls)
+	}
+
+	//ни память, ни постоянный уровень не должны отдавать ответ прежнего api
+	fetcher.url = "http://new.example/info"
+	cache.SetScope(fetcher.url)
+
+	detail, err := cache.Fetch(context.Background(), "muse ", "UPRISING")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if detail.Link != "http://new.example/info" {
+		t.Errorf("got link %q after the source changed, want the new source", detail.Link)
+	}
+	if fetcher.calls != 2 {
+		t.Errorf("got %d fetches, want 2", fetcher.calls)
+	}
+	if len(store) != 2 {
+		t.Errorf("store has %d entries, want one per source", len(store))
+	}
+}
+
+func TestCacheReportsStoreErrors(t *testing.T) {
+	fetcher := &urlFetcher{url: "http://example/info"}
+	cache := NewCache(fetcher, brokenStore{}, 10, time.Hour, time.Minute)
+
+	var reported []error
+	cache.OnStoreError(func(err error) {
+		reported = append(reported, err)
+	})
+
+	//ошибки постоянного уровня не мешают ответить из источника
+	detail, err := cache.Fetch(context.Background(), "Muse", "Uprising")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if detail.Link != fetcher.url {
+		t.Errorf("got link %q, want %q", detail.Link, fetcher.url)
+	}
+
+	if len(reported) != 2 {
+		t.Fatalf("got %d reported errors, want get and set", len(reported))
+	}
+	if got := cache.Stats().StoreErrors; got != 2 {
+		t.Errorf("got %d store errors in stats, want 2", got)
+	}
+}
@@ -0,0 +1,108 @@
+// клиент внешнего api с информацией о песнях (releaseDate, text, link)
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	"github.com/Segren/testTask/internal/data"
+	"github.com/Segren/testTask/internal/tracing"
+)
+
+var (
+	ErrNotFound = errors.New("song not found in info provider")
+)
+
+// источник данных о песне: сам клиент или кеш перед ним
+type Fetcher interface {
+	Fetch(ctx context.Context, group, song string) (*data.SongDetail, error)
+}
+
+// источник, доступность которого можно проверить
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+type Client struct {
+	//адрес меняется без перезапуска, поэтому хранится атомарно
+	url  atomic.Pointer[string]
+	HTTP *http.Client
+}
+
+func NewClient(baseURL string, timeout time.Duration) *Client {
+	c := &Client{HTTP: &http.Client{Timeout: timeout}}
+	c.SetURL(baseURL)
+
+	return c
+}
+
+func (c *Client) URL() string {
+	return *c.url.Load()
+}
+
+// меняет адрес внешнего api для следующих запросов
+func (c *Client) SetURL(baseURL string) {
+	c.url.Store(&baseURL)
+}
+
+func (c *Client) Fetch(ctx context.Context, group, song string) (*data.SongDetail, error) {
+	u := fmt.Sprintf("%s?group=%s&song=%s", c.URL(), url.QueryEscape(group), url.QueryEscape(song))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	//внешнее api продолжает трассировку запроса
+	tracing.Inject(ctx, req.Header)
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, ErrNotFound
+	default:
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var detail data.SongDetail
+
+	err = json.NewDecoder(resp.Body).Decode(&detail)
+	if err != nil {
+		return nil, err
+	}
+
+	return &detail, nil
+}
+
+// проверяет, что внешнее api отвечает. Ответ без ошибки сервера (в т.ч. 400 на запрос
+// без параметров) означает, что api доступно
+func (c *Client) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.URL(), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,107 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// лимиты в памяти процесса. При нескольких экземплярах api у каждого свой лимит
+type Memory struct {
+	mu      sync.Mutex
+	clients map[string]*client
+}
+
+type client struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+func NewMemory() *Memory {
+	m := &Memory{clients: make(map[string]*client)}
+
+	//горутина удалящая старые данные из карты клиентов каждую минуту
+	go func() {
+		for {
+			time.Sleep(time.Minute)
+
+			m.mu.Lock()
+
+			for key, client := range m.clients {
+				if time.Since(client.lastSeen) > 3*time.Minute {
+					delete(m.clients, key)
+				}
+			}
+
+			m.mu.Unlock()
+		}
+	}()
+
+	return m
+}
+
+func (m *Memory) Allow(_ context.Context, key string, limit Limit) (Result, error) {
+	now := time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, found := m.clients[key]
+	if !found {
+		c = &client{limiter: rate.NewLimiter(rate.Limit(limit.Rate), limit.Burst)}
+		m.clients[key] = c
+	}
+
+	//лимит мог измениться с момента создания лимитера
+	if c.limiter.Limit() != rate.Limit(limit.Rate) {
+		c.limiter.SetLimitAt(now, rate.Limit(limit.Rate))
+	}
+	if c.limiter.Burst() != limit.Burst {
+		c.limiter.SetBurstAt(now, limit.Burst)
+	}
+
+	c.lastSeen = now
+
+	allowed := c.limiter.AllowN(now, 1)
+	tokens := c.limiter.TokensAt(now)
+
+	result := Result{
+		Allowed:   allowed,
+		Limit:     limit.Burst,
+		Remaining: max(0, int(tokens)),
+		Reset:     secondsToDuration((float64(limit.Burst) - tokens) / limit.Rate),
+	}
+
+	if !allowed {
+		result.RetryAfter = secondsToDuration((1 - tokens) / limit.Rate)
+	}
+
+	return result, nil
+}
+
+func (m *Memory) Peek(_ context.Context, key string, limit Limit) (Result, error) {
+	now := time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tokens := float64(limit.Burst)
+	if c, found := m.clients[key]; found {
+		tokens = min(tokens, c.limiter.TokensAt(now))
+	}
+
+	result := Result{
+		Allowed:   tokens >= 1,
+		Limit:     limit.Burst,
+		Remaining: max(0, int(tokens)),
+		Reset:     secondsToDuration((float64(limit.Burst) - tokens) / limit.Rate),
+	}
+
+	if !result.Allowed {
+		result.RetryAfter = secondsToDuration((1 - tokens) / limit.Rate)
+	}
+
+	return result, nil
+}
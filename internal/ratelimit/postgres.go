@@ -0,0 +1,161 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// лимиты в postgres по алгоритму GCRA, общие для всех экземпляров api.
+// Для каждого клиента хранится только теоретическое время прихода следующего запроса (tat)
+type Postgres struct {
+	DB *sql.DB
+}
+
+func NewPostgres(db *sql.DB) *Postgres {
+	p := &Postgres{DB: db}
+
+	//устаревшие строки эквивалентны полному лимиту, удаляем их раз в минуту.
+	//Ошибка очистки не влияет на проверку лимитов, строки удалятся при следующей попытке
+	go func() {
+		for {
+			time.Sleep(time.Minute)
+
+			_ = p.deleteExpired()
+		}
+	}()
+
+	return p
+}
+
+func (p *Postgres) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	interval, tolerance := gcraParams(limit)
+
+	//решение принимается одним запросом: строка обновляется, только если запрос укладывается в лимит.
+	//Параллельные запросы клиента ждут блокировку строки внутри ON CONFLICT и видят уже новый tat.
+	//Для отклоненного запроса tat читается из той же строки без изменения
+	query := `
+		WITH upsert AS (
+			INSERT INTO rate_limits AS r (key, tat)
+			VALUES ($1, NOW() + $2::float8 * interval '1 microsecond')
+			ON CONFLICT (key) DO UPDATE
+			SET tat = GREATEST(r.tat, NOW()) + $2::float8 * interval '1 microsecond'
+			WHERE GREATEST(r.tat, NOW()) + ($2::float8 - $3::float8) * interval '1 microsecond' <= NOW()
+			RETURNING tat
+		)
+		SELECT true, tat, NOW() FROM upsert
+		UNION ALL
+		SELECT false, tat, NOW() FROM rate_limits
+		WHERE key = $1 AND NOT EXISTS (SELECT 1 FROM upsert)`
+
+	var allowed bool
+	var tat, now time.Time
+
+	err := p.DB.QueryRowContext(ctx, query, key, interval.Microseconds(), tolerance.Microseconds()).Scan(&allowed, &tat, &now)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		//строку только что создал параллельный запрос, и она не попала в снимок данных запроса:
+		//ее tat не раньше now + interval
+		now = time.Now()
+		tat = now.Add(interval)
+	case err != nil:
+		return Result{}, err
+	case allowed:
+		//возвращен уже сдвинутый tat, для расчета заголовков нужен исходный
+		tat = tat.Add(-interval)
+	}
+
+	_, result := gcra(tat, now, limit)
+
+	return result, nil
+}
+
+// интервал между запросами и допустимое опережение графика. Округляются до микросекунд -
+// точности timestamp в postgres, чтобы решение в sql совпадало с gcra
+func gcraParams(limit Limit) (interval, tolerance time.Duration) {
+	interval = secondsToDuration(1 / limit.Rate).Truncate(time.Microsecond)
+	tolerance = interval * time.Duration(limit.Burst)
+
+	return interval, tolerance
+}
+
+// проверка запроса, пришедшего в now, при сохраненном tat. Если запрос разрешен,
+// вместо tat нужно сохранить возвращенное время
+func gcra(tat, now time.Time, limit Limit) (time.Time, Result) {
+	interval, tolerance := gcraParams(limit)
+
+	if tat.Before(now) {
+		tat = now
+	}
+
+	newTAT := tat.Add(interval)
+
+	if allowAt := newTAT.Add(-tolerance); now.Before(allowAt) {
+		return tat, Result{
+			Allowed:    false,
+			Limit:      limit.Burst,
+			Remaining:  0,
+			Reset:      tat.Sub(now),
+			RetryAfter: allowAt.Sub(now),
+		}
+	}
+
+	return newTAT, Result{
+		Allowed:   true,
+		Limit:     limit.Burst,
+		Remaining: int((tolerance - newTAT.Sub(now)) / interval),
+		Reset:     newTAT.Sub(now),
+	}
+}
+
+func (p *Postgres) Peek(ctx context.Context, key string, limit Limit) (Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	query := `
+		SELECT COALESCE((SELECT tat FROM rate_limits WHERE key = $1), NOW()), NOW()`
+
+	var tat, now time.Time
+
+	err := p.DB.QueryRowContext(ctx, query, key).Scan(&tat, &now)
+	if err != nil {
+		return Result{}, err
+	}
+
+	interval, tolerance := gcraParams(limit)
+
+	if tat.Before(now) {
+		tat = now
+	}
+
+	result := Result{
+		Allowed:   true,
+		Limit:     limit.Burst,
+		Remaining: int((tolerance - tat.Sub(now)) / interval),
+		Reset:     tat.Sub(now),
+	}
+
+	if allowAt := tat.Add(interval - tolerance); now.Before(allowAt) {
+		result.Allowed = false
+		result.Remaining = 0
+		result.RetryAfter = allowAt.Sub(now)
+	}
+
+	return result, nil
+}
+
+func (p *Postgres) deleteExpired() error {
+	query := `
+		DELETE FROM rate_limits
+		WHERE tat < NOW() - interval '1 minute'`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := p.DB.ExecContext(ctx, query)
+	return err
+}
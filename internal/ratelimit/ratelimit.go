@@ -0,0 +1,50 @@
+// ограничение частоты запросов с хранением состояния в памяти процесса или в postgres
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// лимит запросов: Rate в секунду в среднем и Burst за раз
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// лимит, составляющий ratio от исходного (например, для изменяющих запросов)
+func (l Limit) Scale(ratio float64) Limit {
+	return Limit{
+		Rate:  l.Rate * ratio,
+		Burst: max(1, int(float64(l.Burst)*ratio)),
+	}
+}
+
+func (l Limit) String() string {
+	return fmt.Sprintf("%g:%d", l.Rate, l.Burst)
+}
+
+// результат проверки лимита
+type Result struct {
+	Allowed bool
+	Limit   int
+	//оставшиеся запросы
+	Remaining int
+	//время до полного восстановления лимита
+	Reset time.Duration
+	//время до следующего разрешенного запроса, если запрос отклонен
+	RetryAfter time.Duration
+}
+
+// хранилище состояния лимитов. Allow тратит один запрос из лимита клиента key,
+// Peek только сообщает, был бы запрос разрешен
+type Store interface {
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+	Peek(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(math.Max(0, seconds) * float64(time.Second))
+}
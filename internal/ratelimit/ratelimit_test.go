@@ -0,0 +1,189 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestGCRA(t *testing.T) {
+	//1 запрос в секунду, до 3 подряд
+	limit := Limit{Rate: 1, Burst: 3}
+	start := time.Date(2024, 11, 22, 12, 0, 0, 0, time.UTC)
+
+	steps := []struct {
+		name       string
+		at         time.Duration
+		allowed    bool
+		remaining  int
+		retryAfter time.Duration
+	}{
+		{"first", 0, true, 2, 0},
+		{"burst 2", 0, true, 1, 0},
+		{"burst 3", 0, true, 0, 0},
+		{"over burst", 0, false, 0, time.Second},
+		{"still early", 500 * time.Millisecond, false, 0, 500 * time.Millisecond},
+		{"one refilled", time.Second, true, 0, 0},
+		{"again empty", time.Second, false, 0, time.Second},
+		//после простоя восстанавливается весь запас, но не больше Burst
+		{"after idle", time.Minute, true, 2, 0},
+		{"after idle 2", time.Minute, true, 1, 0},
+		{"after idle 3", time.Minute, true, 0, 0},
+		{"after idle 4", time.Minute, false, 0, time.Second},
+	}
+
+	tat := start
+
+	for _, step := range steps {
+		newTAT, result := gcra(tat, start.Add(step.at), limit)
+
+		if result.Allowed != step.allowed || result.Remaining != step.remaining || result.RetryAfter != step.retryAfter {
+			t.Fatalf("%s: got allowed %t, remaining %d, retry after %v; want %t, %d, %v",
+				step.name, result.Allowed, result.Remaining, result.RetryAfter, step.allowed, step.remaining, step.retryAfter)
+		}
+		if result.Limit != limit.Burst {
+			t.Fatalf("%s: got limit %d, want %d", step.name, result.Limit, limit.Burst)
+		}
+
+		if result.Allowed {
+			tat = newTAT
+		}
+	}
+}
+
+func TestGCRAFractionalRate(t *testing.T) {
+	//один запрос в 5 секунд, как у лимита неудачных входов
+	limit := Limit{Rate: 0.2, Burst: 1}
+	now := time.Date(2024, 11, 22, 12, 0, 0, 0, time.UTC)
+
+	tat, result := gcra(now, now, limit)
+	if !result.Allowed || result.Reset != 5*time.Second {
+		t.Fatalf("first request: got %+v", result)
+	}
+
+	_, result = gcra(tat, now.Add(4*time.Second), limit)
+	if result.Allowed || result.RetryAfter != time.Second {
+		t.Fatalf("after 4s: got %+v, want retry after 1s", result)
+	}
+
+	_, result = gcra(tat, now.Add(5*time.Second), limit)
+	if !result.Allowed {
+		t.Fatalf("after 5s: got %+v, want allowed", result)
+	}
+}
+
+// Postgres.Allow получает из sql уже сдвинутый tat и восстанавливает результат по tat - interval
+func TestGCRAFromAdvancedTAT(t *testing.T) {
+	limits := []Limit{{Rate: 1, Burst: 3}, {Rate: 0.2, Burst: 1}, {Rate: 3, Burst: 2}, {Rate: 7, Burst: 5}}
+	start := time.Date(2024, 11, 22, 12, 0, 0, 0, time.UTC)
+
+	for _, limit := range limits {
+		interval, _ := gcraParams(limit)
+		tat := start
+
+		for i := range 20 {
+			now := start.Add(time.Duration(i) * 150 * time.Millisecond)
+
+			newTAT, want := gcra(tat, now, limit)
+			if !want.Allowed {
+				continue
+			}
+
+			_, got := gcra(newTAT.Add(-interval), now, limit)
+			if got != want {
+				t.Fatalf("limit %+v, request %d: got %+v, want %+v", limit, i, got, want)
+			}
+
+			tat = newTAT
+		}
+	}
+}
+
+func TestMemoryBurstAndRefill(t *testing.T) {
+	m := NewMemory()
+	ctx := context.Background()
+
+	//за время теста запас почти не восстанавливается
+	slow := Limit{Rate: 0.001, Burst: 3}
+
+	for i := range 3 {
+		result, err := m.Allow(ctx, "burst", slow)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !result.Allowed || result.Remaining != 2-i {
+			t.Fatalf("request %d: got %+v", i+1, result)
+		}
+	}
+
+	result, _ := m.Allow(ctx, "burst", slow)
+	if result.Allowed || result.RetryAfter <= 0 {
+		t.Fatalf("over burst: got %+v, want denied with RetryAfter", result)
+	}
+
+	//у другого ключа свой запас
+	result, _ = m.Allow(ctx, "other", slow)
+	if !result.Allowed {
+		t.Fatalf("other key: got %+v, want allowed", result)
+	}
+
+	fast := Limit{Rate: 50, Burst: 1}
+
+	result, _ = m.Allow(ctx, "refill", fast)
+	if !result.Allowed {
+		t.Fatalf("refill first: got %+v", result)
+	}
+	result, _ = m.Allow(ctx, "refill", fast)
+	if result.Allowed {
+		t.Fatalf("refill immediately: got %+v, want denied", result)
+	}
+
+	time.Sleep(40 * time.Millisecond)
+
+	result, _ = m.Allow(ctx, "refill", fast)
+	if !result.Allowed {
+		t.Fatalf("refill after 40ms: got %+v, want allowed", result)
+	}
+}
+
+func TestMemoryPeekDoesNotConsume(t *testing.T) {
+	m := NewMemory()
+	ctx := context.Background()
+	limit := Limit{Rate: 0.001, Burst: 2}
+
+	for range 5 {
+		result, err := m.Peek(ctx, "key", limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !result.Allowed || result.Remaining != 2 {
+			t.Fatalf("peek: got %+v, want allowed with 2 remaining", result)
+		}
+	}
+
+	m.Allow(ctx, "key", limit)
+	m.Allow(ctx, "key", limit)
+
+	result, _ := m.Peek(ctx, "key", limit)
+	if result.Allowed || result.RetryAfter <= 0 {
+		t.Fatalf("peek after burst: got %+v, want denied", result)
+	}
+}
+
+func TestLimitScale(t *testing.T) {
+	tests := []struct {
+		limit Limit
+		ratio float64
+		want  Limit
+	}{
+		{Limit{Rate: 20, Burst: 50}, 0.25, Limit{Rate: 5, Burst: 12}},
+		{Limit{Rate: 0.2, Burst: 1}, 0.25, Limit{Rate: 0.05, Burst: 1}},
+		{Limit{Rate: 40, Burst: 100}, 1, Limit{Rate: 40, Burst: 100}},
+	}
+
+	for _, tt := range tests {
+		if got := tt.limit.Scale(tt.ratio); got != tt.want {
+			t.Errorf("%v.Scale(%g) = %v, want %v", tt.limit, tt.ratio, got, tt.want)
+		}
+	}
+}
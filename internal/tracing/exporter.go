@@ -0,0 +1,72 @@
+package tracing
+
+import (
+	"encoding/json"
+	"io"
+	"maps"
+	"sync"
+	"time"
+)
+
+// завершенный спан в виде для выгрузки
+type SpanData struct {
+	TraceID      string            `json:"trace_id"`
+	SpanID       string            `json:"span_id"`
+	ParentSpanID string            `json:"parent_span_id,omitempty"`
+	Name         string            `json:"name"`
+	Start        time.Time         `json:"start"`
+	End          time.Time         `json:"end"`
+	Duration     time.Duration     `json:"duration_ns"`
+	Attributes   map[string]string `json:"attributes,omitempty"`
+	Error        string            `json:"error,omitempty"`
+}
+
+func (s *Span) Data() SpanData {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d := SpanData{
+		TraceID:    s.sc.TraceID.String(),
+		SpanID:     s.sc.SpanID.String(),
+		Name:       s.name,
+		Start:      s.start,
+		End:        s.end,
+		Duration:   s.end.Sub(s.start),
+		Attributes: maps.Clone(s.attributes),
+	}
+
+	if s.parent.IsValid() {
+		d.ParentSpanID = s.parent.String()
+	}
+
+	if s.err != nil {
+		d.Error = s.err.Error()
+	}
+
+	return d
+}
+
+// пишет каждый спан одной строкой json. Для локальной отладки
+type JSONExporter struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+func NewJSONExporter(out io.Writer) *JSONExporter {
+	return &JSONExporter{out: out}
+}
+
+func (e *JSONExporter) Export(span *Span) error {
+	line, err := json.Marshal(struct {
+		Span SpanData `json:"span"`
+	}{span.Data()})
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	_, err = e.out.Write(append(line, '\n'))
+	return err
+}
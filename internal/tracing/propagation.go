@@ -0,0 +1,75 @@
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// заголовок W3C Trace Context: 00-<trace-id>-<parent-id>-<flags>
+const TraceparentHeader = "Traceparent"
+
+const flagSampled = 0x01
+
+// контекст вызывающего сервиса из заголовка traceparent. Невалидный заголовок
+// игнорируется, тогда начинается новая трассировка
+func Extract(h http.Header) SpanContext {
+	parts := strings.Split(strings.TrimSpace(h.Get(TraceparentHeader)), "-")
+	if len(parts) < 4 {
+		return SpanContext{}
+	}
+
+	version, err := hex.DecodeString(parts[0])
+	//версия ff запрещена, у версии 00 ровно 4 поля
+	if err != nil || len(version) != 1 || version[0] == 0xff || (version[0] == 0 && len(parts) != 4) {
+		return SpanContext{}
+	}
+
+	var sc SpanContext
+
+	if !decodeHex(sc.TraceID[:], parts[1]) || !decodeHex(sc.SpanID[:], parts[2]) {
+		return SpanContext{}
+	}
+
+	var flags [1]byte
+	if !decodeHex(flags[:], parts[3]) {
+		return SpanContext{}
+	}
+	sc.Sampled = flags[0]&flagSampled != 0
+
+	if !sc.IsValid() {
+		return SpanContext{}
+	}
+
+	return sc
+}
+
+// передает спан из контекста в заголовке traceparent исходящего запроса
+func Inject(ctx context.Context, h http.Header) {
+	span := SpanFromContext(ctx)
+	if span == nil {
+		return
+	}
+
+	h.Set(TraceparentHeader, span.SpanContext().Traceparent())
+}
+
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + flags
+}
+
+// декодирует строку из строчных hex символов ровно в len(dst) байт
+func decodeHex(dst []byte, s string) bool {
+	if len(s) != 2*len(dst) || strings.ToLower(s) != s {
+		return false
+	}
+
+	_, err := hex.Decode(dst, []byte(s))
+	return err == nil
+}
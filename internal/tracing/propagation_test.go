@@ -0,0 +1,92 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestExtract(t *testing.T) {
+	const (
+		traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+		spanID  = "00f067aa0ba902b7"
+	)
+
+	tests := []struct {
+		name        string
+		traceparent string
+		valid       bool
+		sampled     bool
+	}{
+		{"sampled", "00-" + traceID + "-" + spanID + "-01", true, true},
+		{"not sampled", "00-" + traceID + "-" + spanID + "-00", true, false},
+		{"other flags", "00-" + traceID + "-" + spanID + "-03", true, true},
+		{"surrounding spaces", " 00-" + traceID + "-" + spanID + "-01 ", true, true},
+		//будущие версии могут добавлять поля
+		{"future version with extra field", "01-" + traceID + "-" + spanID + "-01-extra", true, true},
+		{"missing", "", false, false},
+		{"too few fields", "00-" + traceID + "-" + spanID, false, false},
+		{"version 00 with extra field", "00-" + traceID + "-" + spanID + "-01-extra", false, false},
+		{"forbidden version ff", "ff-" + traceID + "-" + spanID + "-01", false, false},
+		{"invalid version", "0g-" + traceID + "-" + spanID + "-01", false, false},
+		{"long version", "000-" + traceID + "-" + spanID + "-01", false, false},
+		{"uppercase trace id", "00-" + "4BF92F3577B34DA6A3CE929D0E0E4736" + "-" + spanID + "-01", false, false},
+		{"short trace id", "00-" + traceID[:30] + "-" + spanID + "-01", false, false},
+		{"short span id", "00-" + traceID + "-" + spanID[:14] + "-01", false, false},
+		{"non hex span id", "00-" + traceID + "-" + "00f067aa0ba902bz" + "-01", false, false},
+		{"zero trace id", "00-00000000000000000000000000000000-" + spanID + "-01", false, false},
+		{"zero span id", "00-" + traceID + "-0000000000000000-01", false, false},
+		{"invalid flags", "00-" + traceID + "-" + spanID + "-1", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := http.Header{}
+			if tt.traceparent != "" {
+				h.Set(TraceparentHeader, tt.traceparent)
+			}
+
+			sc := Extract(h)
+
+			if sc.IsValid() != tt.valid {
+				t.Fatalf("Extract(%q): got valid %t, want %t", tt.traceparent, sc.IsValid(), tt.valid)
+			}
+			if !tt.valid {
+				if sc != (SpanContext{}) {
+					t.Errorf("Extract(%q): got %+v, want zero context", tt.traceparent, sc)
+				}
+				return
+			}
+
+			if sc.TraceID.String() != traceID || sc.SpanID.String() != spanID || sc.Sampled != tt.sampled {
+				t.Errorf("Extract(%q) = %s %s %t", tt.traceparent, sc.TraceID, sc.SpanID, sc.Sampled)
+			}
+		})
+	}
+}
+
+func TestInjectContinuesTrace(t *testing.T) {
+	in := http.Header{}
+	in.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+
+	parent := Extract(in)
+	ctx, span := New(nil, nil).Start(context.Background(), "request", parent)
+
+	out := http.Header{}
+	Inject(ctx, out)
+
+	sc := Extract(out)
+	if sc.TraceID != parent.TraceID || sc.Sampled != parent.Sampled {
+		t.Errorf("injected %q does not continue trace %s", out.Get(TraceparentHeader), parent.TraceID)
+	}
+	if sc.SpanID != span.SpanContext().SpanID || sc.SpanID == parent.SpanID {
+		t.Errorf("injected span id %s, want the new span %s", sc.SpanID, span.SpanContext().SpanID)
+	}
+
+	//без спана в контексте заголовок не добавляется
+	empty := http.Header{}
+	Inject(context.Background(), empty)
+	if empty.Get(TraceparentHeader) != "" {
+		t.Errorf("got traceparent %q without a span", empty.Get(TraceparentHeader))
+	}
+}
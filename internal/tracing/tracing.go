@@ -0,0 +1,193 @@
+// трассировка запросов: спаны с идентификаторами W3C Trace Context,
+// передача контекста через заголовок traceparent и выгрузка спанов в Exporter
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+type TraceID [16]byte
+
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+func (id TraceID) IsValid() bool {
+	return id != TraceID{}
+}
+
+type SpanID [8]byte
+
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+func (id SpanID) IsValid() bool {
+	return id != SpanID{}
+}
+
+// идентификаторы спана, передаваемые между сервисами
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// получатель завершенных спанов
+type Exporter interface {
+	Export(span *Span) error
+}
+
+type Tracer struct {
+	exporter Exporter
+	//вызывается при ошибке выгрузки спана
+	onError func(error)
+}
+
+// трассировщик, выгружающий спаны в exporter. nil exporter - спаны не выгружаются,
+// но идентификаторы трассировки создаются и передаются дальше
+func New(exporter Exporter, onError func(error)) *Tracer {
+	return &Tracer{exporter: exporter, onError: onError}
+}
+
+type Span struct {
+	tracer *Tracer
+	sc     SpanContext
+	parent SpanID
+	name   string
+	start  time.Time
+
+	mu         sync.Mutex
+	end        time.Time
+	attributes map[string]string
+	err        error
+	ended      bool
+}
+
+type spanContextKey struct{}
+
+// спан из контекста или nil
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanContextKey{}).(*Span)
+	return span
+}
+
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, span)
+}
+
+// начинает корневой спан запроса. Если parent валиден (пришел в traceparent),
+// спан продолжает трассировку вызывающего сервиса
+func (t *Tracer) Start(ctx context.Context, name string, parent SpanContext) (context.Context, *Span) {
+	span := &Span{tracer: t, name: name, start: time.Now()}
+
+	if parent.IsValid() {
+		span.sc.TraceID = parent.TraceID
+		span.sc.Sampled = parent.Sampled
+		span.parent = parent.SpanID
+	} else {
+		rand.Read(span.sc.TraceID[:])
+		span.sc.Sampled = true
+	}
+	rand.Read(span.sc.SpanID[:])
+
+	return ContextWithSpan(ctx, span), span
+}
+
+// начинает дочерний спан спана из контекста. Без спана в контексте возвращает nil:
+// методы nil спана ничего не делают, поэтому вызывающему коду проверки не нужны
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	parent := SpanFromContext(ctx)
+	if parent == nil {
+		return ctx, nil
+	}
+
+	span := &Span{
+		tracer: parent.tracer,
+		sc:     SpanContext{TraceID: parent.sc.TraceID, Sampled: parent.sc.Sampled},
+		parent: parent.sc.SpanID,
+		name:   name,
+		start:  time.Now(),
+	}
+	rand.Read(span.sc.SpanID[:])
+
+	return ContextWithSpan(ctx, span), span
+}
+
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.sc
+}
+
+func (s *Span) SetName(name string) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.name = name
+}
+
+func (s *Span) SetAttribute(key, value string) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.attributes == nil {
+		s.attributes = make(map[string]string)
+	}
+	s.attributes[key] = value
+}
+
+// отмечает спан как завершившийся ошибкой
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.err = err
+}
+
+// завершает спан и выгружает его. Повторные вызовы игнорируются
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.mu.Unlock()
+
+	t := s.tracer
+	if t.exporter == nil || !s.sc.Sampled {
+		return
+	}
+
+	err := t.exporter.Export(s)
+	if err != nil && t.onError != nil {
+		t.onError(err)
+	}
+}
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS provider_cache;
\ No newline at end of file
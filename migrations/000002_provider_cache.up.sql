@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS provider_cache (
+    key text PRIMARY KEY,
+    payload jsonb NOT NULL,
+    not_found boolean NOT NULL DEFAULT false,
+    expires_at timestamp(0) with time zone NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS provider_cache_expires_at_idx ON provider_cache (expires_at);
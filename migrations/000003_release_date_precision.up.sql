@@ -0,0 +1,5 @@
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS release_date_precision text NOT NULL DEFAULT 'day';
+
+ALTER TABLE songs ADD CONSTRAINT songs_release_date_precision_check CHECK (release_date_precision IN ('year', 'month', 'day'));
+
+CREATE INDEX IF NOT EXISTS songs_release_date_idx ON songs (releaseDate);
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS song_links (
+    id bigserial PRIMARY KEY,
+    song_id bigint NOT NULL REFERENCES songs ON DELETE CASCADE,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    type text NOT NULL,
+    url text NOT NULL,
+    added_by text NOT NULL DEFAULT '',
+    last_checked_at timestamp(0) with time zone,
+    failure_count integer NOT NULL DEFAULT 0,
+    dead boolean NOT NULL DEFAULT false,
+    version integer NOT NULL DEFAULT 1,
+    CONSTRAINT song_links_song_id_url_key UNIQUE (song_id, url)
+);
+
+CREATE INDEX IF NOT EXISTS song_links_last_checked_at_idx ON song_links (last_checked_at NULLS FIRST);
+
+-- переносим ссылки, полученные от внешнего api
+INSERT INTO song_links (song_id, type, url, added_by)
+SELECT id, 'other', link, 'info provider'
+FROM songs
+WHERE link IS NOT NULL AND link <> ''
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,11 @@
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS language text NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS song_translations (
+    song_id bigint NOT NULL REFERENCES songs ON DELETE CASCADE,
+    language text NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    title text NOT NULL,
+    text text NOT NULL,
+    version integer NOT NULL DEFAULT 1,
+    PRIMARY KEY (song_id, language)
+);
@@ -0,0 +1,10 @@
+DROP TABLE IF EXISTS plays;
+DROP TABLE IF EXISTS ratings;
+DROP TABLE IF EXISTS favorites;
+
+DROP FUNCTION IF EXISTS songs_count_play();
+DROP FUNCTION IF EXISTS songs_count_rating();
+
+ALTER TABLE songs DROP COLUMN IF EXISTS rating_sum;
+ALTER TABLE songs DROP COLUMN IF EXISTS rating_count;
+ALTER TABLE songs DROP COLUMN IF EXISTS play_count;
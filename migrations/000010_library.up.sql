@@ -0,0 +1,59 @@
+CREATE TABLE IF NOT EXISTS favorites (
+    user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    song_id bigint NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, song_id),
+    CONSTRAINT favorites_song_id_fkey FOREIGN KEY (song_id) REFERENCES songs ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS ratings (
+    user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    song_id bigint NOT NULL,
+    rating smallint NOT NULL CHECK (rating BETWEEN 1 AND 5),
+    updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, song_id),
+    CONSTRAINT ratings_song_id_fkey FOREIGN KEY (song_id) REFERENCES songs ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS plays (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    song_id bigint NOT NULL,
+    played_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    CONSTRAINT plays_song_id_fkey FOREIGN KEY (song_id) REFERENCES songs ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS plays_user_id_played_at_idx ON plays (user_id, played_at DESC);
+CREATE INDEX IF NOT EXISTS plays_song_id_idx ON plays (song_id);
+
+-- счетчики для сортировки по популярности и рейтингу без агрегации на каждый запрос
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS play_count bigint NOT NULL DEFAULT 0;
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS rating_count integer NOT NULL DEFAULT 0;
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS rating_sum bigint NOT NULL DEFAULT 0;
+
+CREATE OR REPLACE FUNCTION songs_count_play() RETURNS trigger AS $$
+BEGIN
+    UPDATE songs SET play_count = play_count + 1 WHERE id = NEW.song_id;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION songs_count_rating() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        UPDATE songs SET rating_count = rating_count - 1, rating_sum = rating_sum - OLD.rating WHERE id = OLD.song_id;
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        UPDATE songs SET rating_count = rating_count + 1, rating_sum = rating_sum + NEW.rating WHERE id = NEW.song_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER plays_count AFTER INSERT ON plays
+FOR EACH ROW EXECUTE FUNCTION songs_count_play();
+
+CREATE TRIGGER ratings_count AFTER INSERT OR UPDATE OR DELETE ON ratings
+FOR EACH ROW EXECUTE FUNCTION songs_count_rating();
+
+CREATE INDEX IF NOT EXISTS songs_play_count_idx ON songs (play_count);
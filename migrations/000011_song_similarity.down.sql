@@ -0,0 +1,2 @@
+DROP MATERIALIZED VIEW IF EXISTS song_similarity;
+DROP INDEX IF EXISTS songs_group_play_count_idx;
@@ -0,0 +1,64 @@
+-- похожие песни: совместные прослушивания (косинусная мера по слушателям) и общая группа/язык.
+-- Обновляется фоновой задачей через REFRESH MATERIALIZED VIEW CONCURRENTLY.
+-- Число пар ограничено до ранжирования, чтобы обновление не росло квадратично:
+-- у каждой песни не больше 50 кандидатов из своей группы (самые популярные, по индексу),
+-- а совместные прослушивания считаются по 200 последним песням каждого пользователя
+CREATE INDEX IF NOT EXISTS songs_group_play_count_idx ON songs ("group", play_count DESC, id);
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS song_similarity AS
+WITH listens AS (
+    SELECT user_id, song_id
+    FROM (
+        SELECT user_id, song_id, row_number() OVER (PARTITION BY user_id ORDER BY max(played_at) DESC) AS recent
+        FROM plays
+        GROUP BY user_id, song_id
+    ) recent_listens
+    WHERE recent <= 200
+),
+listeners AS (
+    SELECT song_id, count(*) AS total FROM listens GROUP BY song_id
+),
+co_listening AS (
+    SELECT a.song_id, b.song_id AS similar_song_id, count(*) AS co_listeners
+    FROM listens a
+    INNER JOIN listens b ON b.user_id = a.user_id AND b.song_id <> a.song_id
+    GROUP BY a.song_id, b.song_id
+),
+pairs AS (
+    SELECT song_id, similar_song_id FROM co_listening
+    UNION
+    SELECT a.id, b.id
+    FROM songs a
+    CROSS JOIN LATERAL (
+        SELECT id FROM songs b
+        WHERE b."group" = a."group" AND b.id <> a.id
+        ORDER BY b.play_count DESC, b.id
+        LIMIT 50
+    ) b
+),
+scored AS (
+    SELECT
+        pairs.song_id,
+        pairs.similar_song_id,
+        COALESCE(co_listening.co_listeners, 0) AS co_listeners,
+        a."group" = b."group" AS same_group,
+        a.language NOT IN ('', 'und') AND a.language = b.language AS same_language,
+        0.7 * COALESCE(co_listening.co_listeners / sqrt(la.total * lb.total), 0)
+            + CASE WHEN a."group" = b."group" THEN 0.2 ELSE 0 END
+            + CASE WHEN a.language NOT IN ('', 'und') AND a.language = b.language THEN 0.1 ELSE 0 END AS score
+    FROM pairs
+    INNER JOIN songs a ON a.id = pairs.song_id
+    INNER JOIN songs b ON b.id = pairs.similar_song_id
+    LEFT JOIN co_listening ON co_listening.song_id = pairs.song_id AND co_listening.similar_song_id = pairs.similar_song_id
+    LEFT JOIN listeners la ON la.song_id = pairs.song_id
+    LEFT JOIN listeners lb ON lb.song_id = pairs.similar_song_id
+)
+SELECT song_id, similar_song_id, co_listeners, same_group, same_language, score
+FROM (
+    SELECT scored.*, row_number() OVER (PARTITION BY song_id ORDER BY score DESC, similar_song_id) AS rank
+    FROM scored
+) ranked
+WHERE rank <= 50;
+
+-- уникальный индекс нужен для REFRESH ... CONCURRENTLY
+CREATE UNIQUE INDEX IF NOT EXISTS song_similarity_song_id_similar_song_id_idx ON song_similarity (song_id, similar_song_id);
@@ -0,0 +1,9 @@
+// sql миграции схемы, встроенные в бинарный файл
+package migrations
+
+import "embed"
+
+// файлы вида 000001_name.up.sql и 000001_name.down.sql
+//
+//go:embed *.sql
+var FS embed.FS
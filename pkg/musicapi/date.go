@@ -0,0 +1,163 @@
+package musicapi
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidDate = errors.New("invalid date")
+)
+
+// точность даты: известен только год, год и месяц или полная дата
+type DatePrecision string
+
+const (
+	DatePrecisionYear  DatePrecision = "year"
+	DatePrecisionMonth DatePrecision = "month"
+	DatePrecisionDay   DatePrecision = "day"
+)
+
+// допустимые форматы и соответствующая им точность
+var dateLayouts = []struct {
+	layout    string
+	precision DatePrecision
+}{
+	{"2006-01-02", DatePrecisionDay},
+	{"02.01.2006", DatePrecisionDay},
+	{time.RFC3339, DatePrecisionDay},
+	{"2006-01", DatePrecisionMonth},
+	{"01.2006", DatePrecisionMonth},
+	{"2006", DatePrecisionYear},
+}
+
+// nullable дата для date колонок. В JSON выводится как "2024", "2024-11" или "2024-11-22"
+// в зависимости от точности, невалидная дата выводится как null
+type Date struct {
+	Time      time.Time
+	Precision DatePrecision
+	Valid     bool
+}
+
+func ParseDate(s string) (Date, error) {
+	s = strings.TrimSpace(s)
+
+	for _, l := range dateLayouts {
+		t, err := time.Parse(l.layout, s)
+		if err == nil {
+			return NewDate(t, l.precision), nil
+		}
+	}
+
+	return Date{}, ErrInvalidDate
+}
+
+// отбрасывает время и лишние для указанной точности части даты
+func NewDate(t time.Time, precision DatePrecision) Date {
+	year, month, day := t.Date()
+
+	switch precision {
+	case DatePrecisionYear:
+		month, day = time.January, 1
+	case DatePrecisionMonth:
+		day = 1
+	default:
+		precision = DatePrecisionDay
+	}
+
+	return Date{
+		Time:      time.Date(year, month, day, 0, 0, 0, 0, time.UTC),
+		Precision: precision,
+		Valid:     true,
+	}
+}
+
+// последний день периода, который покрывает дата (для фильтрации по диапазону)
+func (d Date) End() Date {
+	if !d.Valid {
+		return d
+	}
+
+	end := d
+	switch d.Precision {
+	case DatePrecisionYear:
+		end.Time = d.Time.AddDate(1, 0, -1)
+	case DatePrecisionMonth:
+		end.Time = d.Time.AddDate(0, 1, -1)
+	}
+	end.Precision = DatePrecisionDay
+
+	return end
+}
+
+func (d Date) String() string {
+	if !d.Valid {
+		return ""
+	}
+
+	switch d.Precision {
+	case DatePrecisionYear:
+		return d.Time.Format("2006")
+	case DatePrecisionMonth:
+		return d.Time.Format("2006-01")
+	default:
+		return d.Time.Format("2006-01-02")
+	}
+}
+
+func (d Date) MarshalJSON() ([]byte, error) {
+	if !d.Valid {
+		return []byte("null"), nil
+	}
+
+	return json.Marshal(d.String())
+}
+
+func (d *Date) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		*d = Date{}
+		return nil
+	}
+
+	var s string
+	err := json.Unmarshal(b, &s)
+	if err != nil {
+		return ErrInvalidDate
+	}
+
+	parsed, err := ParseDate(s)
+	if err != nil {
+		return err
+	}
+
+	*d = parsed
+	return nil
+}
+
+// сканирует date колонку. Точность хранится в отдельной колонке и сканируется в d.Precision
+func (d *Date) Scan(value interface{}) error {
+	if value == nil {
+		d.Time, d.Valid = time.Time{}, false
+		return nil
+	}
+
+	t, ok := value.(time.Time)
+	if !ok {
+		return fmt.Errorf("cannot scan %T into Date", value)
+	}
+
+	d.Time, d.Valid = t, true
+	return nil
+}
+
+func (d Date) Value() (driver.Value, error) {
+	if !d.Valid {
+		return nil, nil
+	}
+
+	return d.Time.Format("2006-01-02"), nil
+}
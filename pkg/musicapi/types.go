@@ -0,0 +1,88 @@
+// типы запросов и ответов api. Пакет зависит только от стандартной библиотеки,
+// поэтому его используют и сервер (internal/data), и клиент pkg/musicclient
+package musicapi
+
+import "time"
+
+type Song struct {
+	ID          int64     `json:"id"`
+	CreatedAt   time.Time `json:"-"`
+	Group       string    `json:"group"`
+	Song        string    `json:"name"`
+	ReleaseDate Date      `json:"releaseDate" swaggertype:"string" example:"2024-11-22"`
+	Text        string    `json:"text"`
+	Link        string    `json:"link"`
+	Language    string    `json:"language,omitempty"`
+	//средняя оценка пользователей, nil если оценок нет
+	AverageRating *float64 `json:"averageRating,omitempty"`
+	RatingCount   int      `json:"ratingCount"`
+	PlayCount     int64    `json:"playCount"`
+	Version       int32    `json:"version"`
+}
+
+// метадата для пагинации
+type Metadata struct {
+	CurrentPage  int `json:"currentPage,omitempty"`
+	PageSize     int `json:"page_size,omitempty"`
+	FirstPage    int `json:"first_page,omitempty"`
+	LastPage     int `json:"last_page,omitempty"`
+	TotalRecords int `json:"total_records,omitempty"`
+}
+
+// внешняя ссылка на песню. Dead выставляется фоновой проверкой после нескольких неудач подряд
+type Link struct {
+	ID            int64      `json:"id"`
+	SongID        int64      `json:"song_id"`
+	Type          string     `json:"type"`
+	URL           string     `json:"url"`
+	AddedBy       string     `json:"added_by,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	LastCheckedAt *time.Time `json:"last_checked_at,omitempty"`
+	FailureCount  int        `json:"failure_count"`
+	Dead          bool       `json:"dead"`
+	Version       int32      `json:"version"`
+}
+
+// перевод названия и текста песни на другой язык
+type Translation struct {
+	SongID    int64     `json:"song_id"`
+	Language  string    `json:"language"`
+	Title     string    `json:"title"`
+	Text      string    `json:"text"`
+	CreatedAt time.Time `json:"-"`
+	Version   int32     `json:"version"`
+}
+
+// похожая песня с объяснением, почему она похожа
+type SimilarSong struct {
+	ID      int64    `json:"id"`
+	Group   string   `json:"group"`
+	Song    string   `json:"name"`
+	Score   float64  `json:"score"`
+	Reasons []string `json:"reasons"`
+}
+
+// рекомендованная песня и песня из библиотеки пользователя, из-за которой она рекомендована
+type Recommendation struct {
+	ID     int64   `json:"id"`
+	Group  string  `json:"group"`
+	Song   string  `json:"name"`
+	Score  float64 `json:"score"`
+	Reason string  `json:"reason"`
+}
+
+// оценка песни пользователем от 1 до 5
+type Rating struct {
+	SongID    int64     `json:"song_id"`
+	Rating    int       `json:"rating"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// прослушивание песни пользователем
+type Play struct {
+	ID       int64     `json:"id"`
+	SongID   int64     `json:"song_id"`
+	Group    string    `json:"group,omitempty"`
+	Song     string    `json:"name,omitempty"`
+	PlayedAt time.Time `json:"played_at"`
+}
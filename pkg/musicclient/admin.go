@@ -0,0 +1,103 @@
+package musicclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// GET /livez (то же, что /healthcheck)
+func (c *Client) Liveness(ctx context.Context) (*Liveness, error) {
+	var resp Liveness
+
+	err := c.do(ctx, http.MethodGet, "/livez", nil, nil, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// GET /readyz. Ответ 503 с состоянием зависимостей возвращается без ошибки
+func (c *Client) Readiness(ctx context.Context) (*Readiness, error) {
+	resp, err := c.send(ctx, http.MethodGet, "/readyz", nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusServiceUnavailable {
+		return nil, decodeError(resp)
+	}
+
+	var readiness Readiness
+
+	err = decodeBody(resp, &readiness)
+	if err != nil {
+		return nil, err
+	}
+
+	return &readiness, nil
+}
+
+// GET /admin/users/:id/permissions
+func (c *Client) ListUserPermissions(ctx context.Context, userID int64) (Permissions, error) {
+	return c.permissionsRequest(ctx, http.MethodGet, userID, nil)
+}
+
+// POST /admin/users/:id/permissions, возвращает разрешения после изменения
+func (c *Client) GrantUserPermissions(ctx context.Context, userID int64, codes ...string) (Permissions, error) {
+	return c.permissionsRequest(ctx, http.MethodPost, userID, codes)
+}
+
+// DELETE /admin/users/:id/permissions, возвращает разрешения после изменения
+func (c *Client) RevokeUserPermissions(ctx context.Context, userID int64, codes ...string) (Permissions, error) {
+	return c.permissionsRequest(ctx, http.MethodDelete, userID, codes)
+}
+
+func (c *Client) permissionsRequest(ctx context.Context, method string, userID int64, codes []string) (Permissions, error) {
+	var body any
+	if method != http.MethodGet {
+		body = struct {
+			Permissions []string `json:"permissions"`
+		}{codes}
+	}
+
+	var resp struct {
+		Permissions Permissions `json:"permissions"`
+	}
+
+	err := c.do(ctx, method, fmt.Sprintf("/admin/users/%d/permissions", userID), nil, body, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Permissions, nil
+}
+
+// GET /admin/log-level
+func (c *Client) LogLevel(ctx context.Context) (string, error) {
+	return c.logLevelRequest(ctx, http.MethodGet, nil)
+}
+
+// PUT /admin/log-level: debug, info, warn, error, fatal или off
+func (c *Client) SetLogLevel(ctx context.Context, level string) (string, error) {
+	input := struct {
+		Level string `json:"level"`
+	}{level}
+
+	return c.logLevelRequest(ctx, http.MethodPut, input)
+}
+
+func (c *Client) logLevelRequest(ctx context.Context, method string, body any) (string, error) {
+	var resp struct {
+		Level string `json:"level"`
+	}
+
+	err := c.do(ctx, method, "/admin/log-level", nil, body, &resp)
+	if err != nil {
+		return "", err
+	}
+
+	return resp.Level, nil
+}
@@ -0,0 +1,196 @@
+// Package musicclient - клиент Music API для других go сервисов. Методы повторяют
+// маршруты cmd/api/routes.go и возвращают те же типы, что отдает сервер
+package musicclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Segren/testTask/internal/tracing"
+)
+
+const (
+	// число повторов запроса после ответа 429 по умолчанию
+	DefaultMaxRetries = 3
+	// ожидание перед повтором, если сервер не прислал Retry-After
+	defaultRetryWait = time.Second
+	// максимальный размер тела ответа с ошибкой, который читает клиент
+	maxErrorBodyBytes = 1 << 20
+)
+
+// Client безопасен для одновременного использования, если поля не меняются после
+// первого запроса. Token и APIKey взаимоисключающие: сервер отклоняет запрос с обоими
+type Client struct {
+	BaseURL string
+	HTTP    *http.Client
+	// токен аутентификации (Authorization: Bearer)
+	Token string
+	// api-ключ сервиса (X-API-Key)
+	APIKey string
+	// сколько раз повторять запрос после 429, 0 - не повторять
+	MaxRetries int
+	// верхняя граница ожидания одного повтора, 0 - без ограничения
+	MaxRetryWait time.Duration
+	UserAgent    string
+}
+
+// baseURL - адрес api без завершающего слеша, например http://localhost:4000
+func New(baseURL string) *Client {
+	return &Client{
+		BaseURL:      strings.TrimRight(baseURL, "/"),
+		HTTP:         &http.Client{Timeout: 30 * time.Second},
+		MaxRetries:   DefaultMaxRetries,
+		MaxRetryWait: time.Minute,
+		UserAgent:    "musicclient",
+	}
+}
+
+// отправляет запрос и декодирует ответ в dst. Ответ с кодом 4xx/5xx возвращается как *APIError
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, dst any) error {
+	return c.doWithHeader(ctx, method, path, query, nil, body, dst)
+}
+
+// то же, что do, с дополнительными заголовками запроса
+func (c *Client) doWithHeader(ctx context.Context, method, path string, query url.Values, header http.Header, body, dst any) error {
+	resp, err := c.send(ctx, method, path, query, header, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return decodeError(resp)
+	}
+
+	return decodeBody(resp, dst)
+}
+
+// отправляет запрос, повторяя его после 429. Тело ответа закрывает вызывающий
+func (c *Client) send(ctx context.Context, method, path string, query url.Values, header http.Header, body any) (*http.Response, error) {
+	var payload []byte
+	if body != nil {
+		var err error
+		payload, err = json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("musicclient: encode request: %w", err)
+		}
+	}
+
+	u := c.BaseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	for attempt := 0; ; attempt++ {
+		req, err := c.newRequest(ctx, method, u, payload)
+		if err != nil {
+			return nil, err
+		}
+
+		for key, values := range header {
+			req.Header[key] = values
+		}
+
+		resp, err := c.HTTP.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode != http.StatusTooManyRequests || attempt >= c.MaxRetries {
+			return resp, nil
+		}
+
+		wait := retryAfter(resp.Header, time.Now())
+		if wait <= 0 {
+			wait = defaultRetryWait << attempt
+		}
+
+		//ждать дольше разрешенного бессмысленно, вызывающий получит ErrRateLimited
+		if c.MaxRetryWait > 0 && wait > c.MaxRetryWait {
+			return resp, nil
+		}
+
+		io.Copy(io.Discard, io.LimitReader(resp.Body, maxErrorBodyBytes))
+		resp.Body.Close()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (c *Client) newRequest(ctx context.Context, method, u string, payload []byte) (*http.Request, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+
+	switch {
+	case c.Token != "":
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	case c.APIKey != "":
+		req.Header.Set("X-API-Key", c.APIKey)
+	}
+
+	//сервер продолжает трассировку вызывающего сервиса
+	tracing.Inject(ctx, req.Header)
+
+	return req, nil
+}
+
+func decodeBody(resp *http.Response, dst any) error {
+	if dst == nil {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	err := json.NewDecoder(resp.Body).Decode(dst)
+	if err != nil {
+		return fmt.Errorf("musicclient: decode %s response: %w", resp.Request.URL.Path, err)
+	}
+
+	return nil
+}
+
+// Retry-After в секундах или в виде даты (RFC 9110)
+func retryAfter(h http.Header, now time.Time) time.Duration {
+	value := h.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(max(seconds, 0)) * time.Second
+	}
+
+	if t, err := http.ParseTime(value); err == nil {
+		return t.Sub(now)
+	}
+
+	return 0
+}
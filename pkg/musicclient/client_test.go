@@ -0,0 +1,163 @@
+package musicclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// сервер отвечает 429 с заголовком retryAfter(), пока не получит limited запросов, потом 200
+func rateLimitedServer(t *testing.T, limited int32, retryAfter func() string) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var calls atomic.Int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= limited {
+			w.Header().Set("Retry-After", retryAfter())
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"error": "rate limit exceeded"}`))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status": "available"}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, &calls
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 11, 22, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		value string
+		want  time.Duration
+	}{
+		{"missing", "", 0},
+		{"seconds", "3", 3 * time.Second},
+		{"zero seconds", "0", 0},
+		{"negative seconds", "-5", 0},
+		{"http date", now.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second},
+		{"date in the past", now.Add(-time.Minute).Format(http.TimeFormat), -time.Minute},
+		{"garbage", "soon", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := http.Header{}
+			if tt.value != "" {
+				h.Set("Retry-After", tt.value)
+			}
+
+			if got := retryAfter(h, now); got != tt.want {
+				t.Errorf("retryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryOnRateLimit(t *testing.T) {
+	tests := []struct {
+		name       string
+		retryAfter func() string
+	}{
+		{"seconds", func() string { return "1" }},
+		//дата с точностью до секунды, поэтому берется с запасом
+		{"http date", func() string { return time.Now().Add(2 * time.Second).UTC().Format(http.TimeFormat) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			srv, calls := rateLimitedServer(t, 1, tt.retryAfter)
+
+			c := New(srv.URL)
+
+			start := time.Now()
+			resp, err := c.Liveness(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if resp.Status != "available" {
+				t.Errorf("got status %q", resp.Status)
+			}
+			if n := calls.Load(); n != 2 {
+				t.Errorf("got %d requests, want 2", n)
+			}
+			if elapsed := time.Since(start); elapsed < 500*time.Millisecond {
+				t.Errorf("retried after %v, Retry-After was ignored", elapsed)
+			}
+		})
+	}
+}
+
+func TestRetryStopsAfterMaxRetries(t *testing.T) {
+	srv, calls := rateLimitedServer(t, 100, func() string { return "0" })
+
+	c := New(srv.URL)
+	c.MaxRetries = 0
+
+	_, err := c.Liveness(context.Background())
+	if !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("got %v, want ErrRateLimited", err)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("got %d requests, want 1", n)
+	}
+}
+
+func TestRetryWaitAboveMaxRetryWait(t *testing.T) {
+	srv, calls := rateLimitedServer(t, 100, func() string { return "120" })
+
+	c := New(srv.URL)
+	c.MaxRetryWait = time.Second
+
+	start := time.Now()
+	_, err := c.Liveness(context.Background())
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("got %v, want *APIError", err)
+	}
+	if apiErr.StatusCode != http.StatusTooManyRequests || apiErr.RetryAfter != 120*time.Second {
+		t.Errorf("got status %d, retry after %v", apiErr.StatusCode, apiErr.RetryAfter)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("got %d requests, want 1", n)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("waited %v before giving up", elapsed)
+	}
+}
+
+func TestRetryWaitCanceled(t *testing.T) {
+	srv, calls := rateLimitedServer(t, 100, func() string { return "60" })
+
+	c := New(srv.URL)
+	c.MaxRetryWait = 0
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := c.Liveness(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want context.DeadlineExceeded", err)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("got %d requests, want 1", n)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("cancellation took %v", elapsed)
+	}
+}
@@ -0,0 +1,117 @@
+package musicclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ошибки по коду ответа, проверяются через errors.Is(err, musicclient.ErrNotFound)
+var (
+	ErrBadRequest       = errors.New("bad request")
+	ErrUnauthorized     = errors.New("authentication required or invalid")
+	ErrForbidden        = errors.New("not permitted")
+	ErrNotFound         = errors.New("not found")
+	ErrMethodNotAllowed = errors.New("method not allowed")
+	ErrEditConflict     = errors.New("edit conflict")
+	ErrValidation       = errors.New("validation failed")
+	ErrRateLimited      = errors.New("rate limit exceeded")
+	ErrBadGateway       = errors.New("info provider returned invalid data")
+	ErrServer           = errors.New("server error")
+)
+
+var statusErrors = map[int]error{
+	http.StatusBadRequest:          ErrBadRequest,
+	http.StatusUnauthorized:        ErrUnauthorized,
+	http.StatusForbidden:           ErrForbidden,
+	http.StatusNotFound:            ErrNotFound,
+	http.StatusMethodNotAllowed:    ErrMethodNotAllowed,
+	http.StatusConflict:            ErrEditConflict,
+	http.StatusUnprocessableEntity: ErrValidation,
+	http.StatusTooManyRequests:     ErrRateLimited,
+	http.StatusBadGateway:          ErrBadGateway,
+}
+
+// ответ api с кодом 4xx/5xx, разобранный из {"error": ...}
+type APIError struct {
+	StatusCode int
+	// сообщение об ошибке, для ошибок валидации - "validation failed"
+	Message string
+	// ошибки по полям запроса (422) или по полям ответа внешнего api (502)
+	Errors map[string]string
+	// X-Request-ID ответа, по нему запрос находится в журнале сервера
+	RequestID string
+	// Retry-After ответа 429
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("musicclient: %d %s", e.StatusCode, e.Message)
+
+	if len(e.Errors) > 0 {
+		fields := make([]string, 0, len(e.Errors))
+		for _, key := range slices.Sorted(maps.Keys(e.Errors)) {
+			fields = append(fields, key+": "+e.Errors[key])
+		}
+		msg += " (" + strings.Join(fields, "; ") + ")"
+	}
+
+	return msg
+}
+
+func (e *APIError) Is(target error) bool {
+	if err, ok := statusErrors[e.StatusCode]; ok {
+		return err == target
+	}
+
+	return target == ErrServer && e.StatusCode >= http.StatusInternalServerError
+}
+
+func decodeError(resp *http.Response) error {
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		Message:    strings.ToLower(http.StatusText(resp.StatusCode)),
+		RequestID:  resp.Header.Get("X-Request-ID"),
+		RetryAfter: retryAfter(resp.Header, time.Now()),
+	}
+
+	var env struct {
+		Error json.RawMessage `json:"error"`
+	}
+
+	err := json.NewDecoder(io.LimitReader(resp.Body, maxErrorBodyBytes)).Decode(&env)
+	if err != nil || len(env.Error) == 0 {
+		return apiErr
+	}
+
+	//ошибка бывает строкой, картой ошибок по полям (422) или сообщением с картой (502)
+	var message string
+	if json.Unmarshal(env.Error, &message) == nil {
+		apiErr.Message = message
+		return apiErr
+	}
+
+	var detailed struct {
+		Message string            `json:"message"`
+		Errors  map[string]string `json:"errors"`
+	}
+	if json.Unmarshal(env.Error, &detailed) == nil && detailed.Message != "" {
+		apiErr.Message = detailed.Message
+		apiErr.Errors = detailed.Errors
+		return apiErr
+	}
+
+	var fields map[string]string
+	if json.Unmarshal(env.Error, &fields) == nil {
+		apiErr.Message = ErrValidation.Error()
+		apiErr.Errors = fields
+	}
+
+	return apiErr
+}
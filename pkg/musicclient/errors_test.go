@@ -0,0 +1,118 @@
+package musicclient
+
+import (
+	"errors"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDecodeError(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		body        string
+		wantMessage string
+		wantErrors  map[string]string
+	}{
+		{
+			name:        "message",
+			status:      http.StatusNotFound,
+			body:        `{"error": "the requested resource could not be found"}`,
+			wantMessage: "the requested resource could not be found",
+		},
+		{
+			name:        "field errors",
+			status:      http.StatusUnprocessableEntity,
+			body:        `{"error": {"group": "must be provided", "song": "must be provided"}}`,
+			wantMessage: "validation failed",
+			wantErrors:  map[string]string{"group": "must be provided", "song": "must be provided"},
+		},
+		{
+			name:        "message with errors",
+			status:      http.StatusBadGateway,
+			body:        `{"error": {"message": "info provider returned invalid data", "errors": {"releaseDate": "must be a valid date"}}}`,
+			wantMessage: "info provider returned invalid data",
+			wantErrors:  map[string]string{"releaseDate": "must be a valid date"},
+		},
+		{
+			name:        "not json",
+			status:      http.StatusBadGateway,
+			body:        `<html>bad gateway</html>`,
+			wantMessage: "bad gateway",
+		},
+		{
+			name:        "empty body",
+			status:      http.StatusInternalServerError,
+			body:        ``,
+			wantMessage: "internal server error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			rr.Header().Set("X-Request-ID", "req-1")
+			rr.WriteHeader(tt.status)
+			rr.WriteString(tt.body)
+
+			err := decodeError(rr.Result())
+
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("got %T, want *APIError", err)
+			}
+
+			if apiErr.StatusCode != tt.status || apiErr.Message != tt.wantMessage || apiErr.RequestID != "req-1" {
+				t.Errorf("got %d %q (request %q), want %d %q", apiErr.StatusCode, apiErr.Message, apiErr.RequestID, tt.status, tt.wantMessage)
+			}
+			if !maps.Equal(apiErr.Errors, tt.wantErrors) {
+				t.Errorf("got errors %v, want %v", apiErr.Errors, tt.wantErrors)
+			}
+		})
+	}
+}
+
+func TestDecodeErrorRetryAfter(t *testing.T) {
+	rr := httptest.NewRecorder()
+	rr.Header().Set("Retry-After", "7")
+	rr.WriteHeader(http.StatusTooManyRequests)
+	rr.WriteString(`{"error": "rate limit exceeded"}`)
+
+	err := decodeError(rr.Result())
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.RetryAfter != 7*time.Second {
+		t.Fatalf("got %v, want RetryAfter 7s", err)
+	}
+}
+
+func TestAPIErrorIs(t *testing.T) {
+	tests := []struct {
+		status int
+		target error
+		want   bool
+	}{
+		{http.StatusNotFound, ErrNotFound, true},
+		{http.StatusNotFound, ErrServer, false},
+		{http.StatusConflict, ErrEditConflict, true},
+		{http.StatusUnprocessableEntity, ErrValidation, true},
+		{http.StatusTooManyRequests, ErrRateLimited, true},
+		{http.StatusBadGateway, ErrBadGateway, true},
+		//502 сопоставлен с ErrBadGateway, а не с общей ошибкой сервера
+		{http.StatusBadGateway, ErrServer, false},
+		{http.StatusInternalServerError, ErrServer, true},
+		{http.StatusServiceUnavailable, ErrServer, true},
+		{http.StatusTeapot, ErrBadRequest, false},
+		{http.StatusTeapot, ErrServer, false},
+	}
+
+	for _, tt := range tests {
+		err := error(&APIError{StatusCode: tt.status})
+		if got := errors.Is(err, tt.target); got != tt.want {
+			t.Errorf("errors.Is(%d, %v) = %t, want %t", tt.status, tt.target, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package musicclient
+
+import (
+	"context"
+	"iter"
+)
+
+// обходит все страницы начиная с start.Page. Ошибка возвращается последним элементом,
+// после нее обход прекращается
+func paginate[T any](ctx context.Context, start Page, fetch func(ctx context.Context, page Page) ([]T, Metadata, error)) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		page := start
+		if page.Page < 1 {
+			page.Page = 1
+		}
+
+		for {
+			items, metadata, err := fetch(ctx, page)
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+
+			for _, item := range items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+
+			//пустая выборка не содержит метаданных
+			if len(items) == 0 || page.Page >= metadata.LastPage {
+				return
+			}
+			page.Page++
+		}
+	}
+}
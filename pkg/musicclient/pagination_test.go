@@ -0,0 +1,103 @@
+package musicclient
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+// страницы по два элемента: 1..lastPage, на последней может быть меньше
+func pagedFetch(items []int, lastPage int, failOn int, requested *[]int) func(context.Context, Page) ([]int, Metadata, error) {
+	return func(ctx context.Context, page Page) ([]int, Metadata, error) {
+		*requested = append(*requested, page.Page)
+
+		if page.Page == failOn {
+			return nil, Metadata{}, errors.New("boom")
+		}
+
+		start := (page.Page - 1) * 2
+		if start >= len(items) {
+			return nil, Metadata{}, nil
+		}
+		end := min(start+2, len(items))
+
+		return items[start:end], Metadata{CurrentPage: page.Page, LastPage: lastPage}, nil
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	tests := []struct {
+		name          string
+		items         []int
+		lastPage      int
+		start         int
+		failOn        int
+		want          []int
+		wantErr       bool
+		wantRequested []int
+	}{
+		{
+			name: "all pages", items: []int{1, 2, 3, 4, 5}, lastPage: 3,
+			want: []int{1, 2, 3, 4, 5}, wantRequested: []int{1, 2, 3},
+		},
+		{
+			name: "from the given page", items: []int{1, 2, 3, 4, 5}, lastPage: 3, start: 2,
+			want: []int{3, 4, 5}, wantRequested: []int{2, 3},
+		},
+		{
+			name: "empty result", items: nil, lastPage: 0,
+			want: nil, wantRequested: []int{1},
+		},
+		{
+			//пустая страница без метаданных останавливает обход, даже если last_page больше
+			name: "empty page before last page", items: []int{1, 2}, lastPage: 5,
+			want: []int{1, 2}, wantRequested: []int{1, 2},
+		},
+		{
+			name: "error", items: []int{1, 2, 3, 4, 5}, lastPage: 3, failOn: 2,
+			want: []int{1, 2}, wantErr: true, wantRequested: []int{1, 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requested []int
+			var got []int
+			var gotErr error
+
+			for item, err := range paginate(context.Background(), Page{Page: tt.start}, pagedFetch(tt.items, tt.lastPage, tt.failOn, &requested)) {
+				if err != nil {
+					gotErr = err
+					continue
+				}
+				got = append(got, item)
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got items %v, want %v", got, tt.want)
+			}
+			if (gotErr != nil) != tt.wantErr {
+				t.Errorf("got error %v, want error %t", gotErr, tt.wantErr)
+			}
+			if !slices.Equal(requested, tt.wantRequested) {
+				t.Errorf("requested pages %v, want %v", requested, tt.wantRequested)
+			}
+		})
+	}
+}
+
+func TestPaginateBreak(t *testing.T) {
+	var requested []int
+
+	for item := range paginate(context.Background(), Page{}, pagedFetch([]int{1, 2, 3, 4, 5}, 3, 0, &requested)) {
+		if item == 3 {
+			break
+		}
+	}
+
+	//после break следующая страница не запрашивается
+	if !slices.Equal(requested, []int{1, 2}) {
+		t.Errorf("requested pages %v, want [1 2]", requested)
+	}
+}
@@ -0,0 +1,262 @@
+package musicclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"net/http"
+	"net/url"
+)
+
+// GET /songs
+func (c *Client) ListSongs(ctx context.Context, filter SongFilter) ([]*Song, Metadata, error) {
+	var resp struct {
+		Songs    []*Song  `json:"songs"`
+		Metadata Metadata `json:"metadata"`
+	}
+
+	err := c.do(ctx, http.MethodGet, "/songs", filter.query(), nil, &resp)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	return resp.Songs, resp.Metadata, nil
+}
+
+// все песни, подходящие под filter, постранично начиная с filter.Page
+func (c *Client) Songs(ctx context.Context, filter SongFilter) iter.Seq2[*Song, error] {
+	return paginate(ctx, filter.Page, func(ctx context.Context, page Page) ([]*Song, Metadata, error) {
+		filter.Page = page
+		return c.ListSongs(ctx, filter)
+	})
+}
+
+// POST /songs. Дата выхода, текст и ссылка запрашиваются сервером у внешнего api
+func (c *Client) CreateSong(ctx context.Context, group, song string) (*Song, error) {
+	input := struct {
+		Group string `json:"group"`
+		Song  string `json:"song"`
+	}{group, song}
+
+	var resp struct {
+		Song *Song `json:"song"`
+	}
+
+	err := c.do(ctx, http.MethodPost, "/songs", nil, input, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Song, nil
+}
+
+// изменения PUT /songs/:id, nil поля не меняются
+type SongUpdate struct {
+	Group *string `json:"group,omitempty"`
+	Song  *string `json:"song,omitempty"`
+}
+
+// PUT /songs/:id
+func (c *Client) UpdateSong(ctx context.Context, id int64, update SongUpdate) (*Song, error) {
+	var resp struct {
+		Song *Song `json:"song"`
+	}
+
+	err := c.do(ctx, http.MethodPut, fmt.Sprintf("/songs/%d", id), nil, update, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Song, nil
+}
+
+// DELETE /songs/:id
+func (c *Client) DeleteSong(ctx context.Context, id int64) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/songs/%d", id), nil, nil, nil)
+}
+
+// GET /songs/:id/lyrics
+func (c *Client) GetLyrics(ctx context.Context, id int64, opts LyricsOptions) (*Lyrics, error) {
+	var header http.Header
+	if opts.AcceptLanguage != "" {
+		header = http.Header{"Accept-Language": {opts.AcceptLanguage}}
+	}
+
+	var resp struct {
+		Language         string          `json:"language"`
+		Title            string          `json:"title"`
+		OriginalLanguage string          `json:"original_language"`
+		OriginalTitle    string          `json:"original_title"`
+		Lyrics           json.RawMessage `json:"lyrics"`
+	}
+
+	err := c.doWithHeader(ctx, http.MethodGet, fmt.Sprintf("/songs/%d/lyrics", id), opts.query(), header, nil, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	lyrics := &Lyrics{
+		Language:         resp.Language,
+		Title:            resp.Title,
+		OriginalLanguage: resp.OriginalLanguage,
+		OriginalTitle:    resp.OriginalTitle,
+	}
+
+	//при side_by_side куплеты приходят парами оригинал/перевод
+	if opts.SideBySide {
+		err = json.Unmarshal(resp.Lyrics, &lyrics.Pairs)
+	} else {
+		err = json.Unmarshal(resp.Lyrics, &lyrics.Verses)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("musicclient: decode lyrics: %w", err)
+	}
+
+	return lyrics, nil
+}
+
+// GET /songs/:id/similar, limit 0 - значение сервера по умолчанию
+func (c *Client) ListSimilarSongs(ctx context.Context, id int64, limit int) ([]*SimilarSong, error) {
+	qs := url.Values{}
+	setInt(qs, "limit", limit)
+
+	var resp struct {
+		Songs []*SimilarSong `json:"songs"`
+	}
+
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("/songs/%d/similar", id), qs, nil, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Songs, nil
+}
+
+// GET /songs/:id/links
+func (c *Client) ListSongLinks(ctx context.Context, songID int64, filter LinkFilter) ([]*Link, error) {
+	var resp struct {
+		Links []*Link `json:"links"`
+	}
+
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("/songs/%d/links", songID), filter.query(), nil, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Links, nil
+}
+
+// POST /songs/:id/links
+func (c *Client) CreateSongLink(ctx context.Context, songID int64, linkType, linkURL string) (*Link, error) {
+	input := struct {
+		Type string `json:"type"`
+		URL  string `json:"url"`
+	}{linkType, linkURL}
+
+	var resp struct {
+		Link *Link `json:"link"`
+	}
+
+	err := c.do(ctx, http.MethodPost, fmt.Sprintf("/songs/%d/links", songID), nil, input, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Link, nil
+}
+
+// GET /songs/:id/links/:link_id
+func (c *Client) GetSongLink(ctx context.Context, songID, linkID int64) (*Link, error) {
+	var resp struct {
+		Link *Link `json:"link"`
+	}
+
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("/songs/%d/links/%d", songID, linkID), nil, nil, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Link, nil
+}
+
+// изменения PATCH /songs/:id/links/:link_id, nil поля не меняются
+type LinkUpdate struct {
+	Type *string `json:"type,omitempty"`
+	URL  *string `json:"url,omitempty"`
+}
+
+// PATCH /songs/:id/links/:link_id
+func (c *Client) UpdateSongLink(ctx context.Context, songID, linkID int64, update LinkUpdate) (*Link, error) {
+	var resp struct {
+		Link *Link `json:"link"`
+	}
+
+	err := c.do(ctx, http.MethodPatch, fmt.Sprintf("/songs/%d/links/%d", songID, linkID), nil, update, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Link, nil
+}
+
+// DELETE /songs/:id/links/:link_id
+func (c *Client) DeleteSongLink(ctx context.Context, songID, linkID int64) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/songs/%d/links/%d", songID, linkID), nil, nil, nil)
+}
+
+// GET /songs/:id/translations
+func (c *Client) ListSongTranslations(ctx context.Context, songID int64) ([]*Translation, error) {
+	var resp struct {
+		Translations []*Translation `json:"translations"`
+	}
+
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("/songs/%d/translations", songID), nil, nil, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Translations, nil
+}
+
+// GET /songs/:id/translations/:lang
+func (c *Client) GetSongTranslation(ctx context.Context, songID int64, lang string) (*Translation, error) {
+	var resp struct {
+		Translation *Translation `json:"translation"`
+	}
+
+	err := c.do(ctx, http.MethodGet, translationPath(songID, lang), nil, nil, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Translation, nil
+}
+
+// PUT /songs/:id/translations/:lang создает или заменяет перевод
+func (c *Client) PutSongTranslation(ctx context.Context, songID int64, lang, title, text string) (*Translation, error) {
+	input := struct {
+		Title string `json:"title"`
+		Text  string `json:"text"`
+	}{title, text}
+
+	var resp struct {
+		Translation *Translation `json:"translation"`
+	}
+
+	err := c.do(ctx, http.MethodPut, translationPath(songID, lang), nil, input, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Translation, nil
+}
+
+// DELETE /songs/:id/translations/:lang
+func (c *Client) DeleteSongTranslation(ctx context.Context, songID int64, lang string) error {
+	return c.do(ctx, http.MethodDelete, translationPath(songID, lang), nil, nil, nil)
+}
+
+func translationPath(songID int64, lang string) string {
+	return fmt.Sprintf("/songs/%d/translations/%s", songID, url.PathEscape(lang))
+}
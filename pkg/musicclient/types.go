@@ -0,0 +1,209 @@
+package musicclient
+
+import (
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/Segren/testTask/pkg/musicapi"
+)
+
+// типы ответов общие с сервером. Пакет musicapi зависит только от стандартной
+// библиотеки, поэтому клиент не тянет драйвер бд и остальные зависимости сервера
+type (
+	Song           = musicapi.Song
+	Date           = musicapi.Date
+	Metadata       = musicapi.Metadata
+	Link           = musicapi.Link
+	Translation    = musicapi.Translation
+	SimilarSong    = musicapi.SimilarSong
+	Recommendation = musicapi.Recommendation
+	Rating         = musicapi.Rating
+	Play           = musicapi.Play
+)
+
+// дата выхода в одном из форматов, которые принимает сервер (YYYY, YYYY-MM, YYYY-MM-DD)
+func ParseDate(s string) (Date, error) {
+	return musicapi.ParseDate(s)
+}
+
+// коды разрешений, например songs:read
+type Permissions []string
+
+// пользователь в ответах /users
+type User struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	Activated bool      `json:"activated"`
+}
+
+// токен аутентификации
+type Token struct {
+	Plaintext string    `json:"token"`
+	Expiry    time.Time `json:"expiry"`
+}
+
+// api-ключ. Plaintext заполнен только в ответах на создание и ротацию
+type APIKey struct {
+	ID         int64       `json:"id"`
+	Name       string      `json:"name"`
+	Prefix     string      `json:"prefix"`
+	Plaintext  string      `json:"key,omitempty"`
+	Scopes     Permissions `json:"scopes"`
+	CreatedAt  time.Time   `json:"created_at"`
+	ExpiresAt  *time.Time  `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time  `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time  `json:"revoked_at,omitempty"`
+	ReplacedBy *int64      `json:"replaced_by,omitempty"`
+}
+
+// номер и размер страницы. Нулевые значения - значения сервера по умолчанию
+type Page struct {
+	Page     int
+	PageSize int
+}
+
+func (p Page) encode(qs url.Values) {
+	setInt(qs, "page", p.Page)
+	setInt(qs, "page_size", p.PageSize)
+}
+
+// параметры GET /songs
+type SongFilter struct {
+	// полнотекстовый поиск по названию
+	Name  string
+	Group string
+	// пустая дата (Valid == false) не ограничивает выборку
+	ReleaseFrom Date
+	ReleaseTo   Date
+	// поле сортировки: id, group, name, releaseDate, popularity, rating, с "-" по убыванию
+	Sort string
+	Page
+}
+
+func (f SongFilter) query() url.Values {
+	qs := url.Values{}
+	setString(qs, "name", f.Name)
+	setString(qs, "group", f.Group)
+	if f.ReleaseFrom.Valid {
+		qs.Set("release_from", f.ReleaseFrom.String())
+	}
+	if f.ReleaseTo.Valid {
+		qs.Set("release_to", f.ReleaseTo.String())
+	}
+	setString(qs, "sort", f.Sort)
+	f.Page.encode(qs)
+
+	return qs
+}
+
+// параметры GET /me/favorites
+type FavoriteFilter struct {
+	// favorited_at или -favorited_at
+	Sort string
+	Page
+}
+
+func (f FavoriteFilter) query() url.Values {
+	qs := url.Values{}
+	setString(qs, "sort", f.Sort)
+	f.Page.encode(qs)
+
+	return qs
+}
+
+// параметры GET /songs/:id/lyrics
+type LyricsOptions struct {
+	// номер куплета и число куплетов на странице
+	Page int
+	Size int
+	// язык перевода (BCP 47), пустая строка - язык оригинала или Accept-Language
+	Lang string
+	// куплеты перевода рядом с куплетами оригинала, требует Lang
+	SideBySide bool
+	// заголовок Accept-Language
+	AcceptLanguage string
+}
+
+func (o LyricsOptions) query() url.Values {
+	qs := url.Values{}
+	setInt(qs, "page", o.Page)
+	setInt(qs, "size", o.Size)
+	setString(qs, "lang", o.Lang)
+	if o.SideBySide {
+		qs.Set("side_by_side", "true")
+	}
+
+	return qs
+}
+
+// текст песни или перевода. Verses заполняется для обычного ответа, Pairs - для SideBySide
+type Lyrics struct {
+	Language         string
+	Title            string
+	OriginalLanguage string
+	OriginalTitle    string
+	Verses           []string
+	Pairs            []VersePair
+}
+
+type VersePair struct {
+	Original    string `json:"original"`
+	Translation string `json:"translation"`
+}
+
+// параметры GET /songs/:id/links
+type LinkFilter struct {
+	Type string
+	// nil - все ссылки, иначе только мертвые или только живые
+	Dead *bool
+}
+
+func (f LinkFilter) query() url.Values {
+	qs := url.Values{}
+	setString(qs, "type", f.Type)
+	if f.Dead != nil {
+		qs.Set("dead", strconv.FormatBool(*f.Dead))
+	}
+
+	return qs
+}
+
+// ответ /livez
+type Liveness struct {
+	Status     string `json:"status"`
+	SystemInfo struct {
+		Environment string `json:"environment"`
+		Version     string `json:"version"`
+	} `json:"system_info"`
+}
+
+// ответ /readyz. Неготовый сервер (503) - это не ошибка, а Ready() == false
+type Readiness struct {
+	Status string                      `json:"status"`
+	Checks map[string]DependencyStatus `json:"checks"`
+}
+
+func (r *Readiness) Ready() bool {
+	return r.Status == "ready"
+}
+
+// состояние зависимости: up, down или degraded (необязательная зависимость недоступна)
+type DependencyStatus struct {
+	Status  string `json:"status"`
+	Latency string `json:"latency"`
+}
+
+func setString(qs url.Values, key, value string) {
+	if value != "" {
+		qs.Set(key, value)
+	}
+}
+
+func setInt(qs url.Values, key string, value int) {
+	if value != 0 {
+		qs.Set(key, strconv.Itoa(value))
+	}
+}
@@ -0,0 +1,262 @@
+package musicclient
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// POST /users. Пользователь создается неактивированным, токен активации приходит на почту
+func (c *Client) RegisterUser(ctx context.Context, name, email, password string) (*User, error) {
+	input := struct {
+		Name     string `json:"name"`
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}{name, email, password}
+
+	var resp struct {
+		User *User `json:"user"`
+	}
+
+	err := c.do(ctx, http.MethodPost, "/users", nil, input, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.User, nil
+}
+
+// PUT /users/activated
+func (c *Client) ActivateUser(ctx context.Context, token string) (*User, error) {
+	input := struct {
+		Token string `json:"token"`
+	}{token}
+
+	var resp struct {
+		User *User `json:"user"`
+	}
+
+	err := c.do(ctx, http.MethodPut, "/users/activated", nil, input, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.User, nil
+}
+
+// POST /tokens/authentication. Для следующих запросов токен нужно записать в c.Token
+func (c *Client) CreateAuthenticationToken(ctx context.Context, email, password string) (*Token, error) {
+	input := struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}{email, password}
+
+	var resp struct {
+		Token *Token `json:"authentication_token"`
+	}
+
+	err := c.do(ctx, http.MethodPost, "/tokens/authentication", nil, input, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Token, nil
+}
+
+// DELETE /tokens/authentication отзывает токен c.Token
+func (c *Client) DeleteAuthenticationToken(ctx context.Context) error {
+	return c.do(ctx, http.MethodDelete, "/tokens/authentication", nil, nil, nil)
+}
+
+// GET /me/favorites
+func (c *Client) ListFavorites(ctx context.Context, filter FavoriteFilter) ([]*Song, Metadata, error) {
+	var resp struct {
+		Songs    []*Song  `json:"songs"`
+		Metadata Metadata `json:"metadata"`
+	}
+
+	err := c.do(ctx, http.MethodGet, "/me/favorites", filter.query(), nil, &resp)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	return resp.Songs, resp.Metadata, nil
+}
+
+// все избранные песни постранично начиная с filter.Page
+func (c *Client) Favorites(ctx context.Context, filter FavoriteFilter) iter.Seq2[*Song, error] {
+	return paginate(ctx, filter.Page, func(ctx context.Context, page Page) ([]*Song, Metadata, error) {
+		filter.Page = page
+		return c.ListFavorites(ctx, filter)
+	})
+}
+
+// PUT /me/favorites/:id
+func (c *Client) AddFavorite(ctx context.Context, songID int64) error {
+	return c.do(ctx, http.MethodPut, fmt.Sprintf("/me/favorites/%d", songID), nil, nil, nil)
+}
+
+// DELETE /me/favorites/:id
+func (c *Client) RemoveFavorite(ctx context.Context, songID int64) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/me/favorites/%d", songID), nil, nil, nil)
+}
+
+// GET /me/ratings
+func (c *Client) ListRatings(ctx context.Context) ([]*Rating, error) {
+	var resp struct {
+		Ratings []*Rating `json:"ratings"`
+	}
+
+	err := c.do(ctx, http.MethodGet, "/me/ratings", nil, nil, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Ratings, nil
+}
+
+// PUT /me/ratings/:id, оценка от 1 до 5
+func (c *Client) RateSong(ctx context.Context, songID int64, rating int) (*Rating, error) {
+	input := struct {
+		Rating int `json:"rating"`
+	}{rating}
+
+	var resp struct {
+		Rating *Rating `json:"rating"`
+	}
+
+	err := c.do(ctx, http.MethodPut, fmt.Sprintf("/me/ratings/%d", songID), nil, input, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Rating, nil
+}
+
+// DELETE /me/ratings/:id
+func (c *Client) DeleteRating(ctx context.Context, songID int64) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/me/ratings/%d", songID), nil, nil, nil)
+}
+
+// GET /me/plays, последние прослушивания первыми
+func (c *Client) ListPlays(ctx context.Context, page Page) ([]*Play, Metadata, error) {
+	qs := url.Values{}
+	page.encode(qs)
+
+	var resp struct {
+		Plays    []*Play  `json:"plays"`
+		Metadata Metadata `json:"metadata"`
+	}
+
+	err := c.do(ctx, http.MethodGet, "/me/plays", qs, nil, &resp)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	return resp.Plays, resp.Metadata, nil
+}
+
+// вся история прослушиваний постранично начиная с start.Page
+func (c *Client) Plays(ctx context.Context, start Page) iter.Seq2[*Play, error] {
+	return paginate(ctx, start, c.ListPlays)
+}
+
+// POST /me/plays
+func (c *Client) RecordPlay(ctx context.Context, songID int64) (*Play, error) {
+	input := struct {
+		SongID int64 `json:"song_id"`
+	}{songID}
+
+	var resp struct {
+		Play *Play `json:"play"`
+	}
+
+	err := c.do(ctx, http.MethodPost, "/me/plays", nil, input, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Play, nil
+}
+
+// GET /me/recommendations, limit 0 - значение сервера по умолчанию
+func (c *Client) ListRecommendations(ctx context.Context, limit int) ([]*Recommendation, error) {
+	qs := url.Values{}
+	setInt(qs, "limit", limit)
+
+	var resp struct {
+		Recommendations []*Recommendation `json:"recommendations"`
+	}
+
+	err := c.do(ctx, http.MethodGet, "/me/recommendations", qs, nil, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Recommendations, nil
+}
+
+// GET /api-keys
+func (c *Client) ListAPIKeys(ctx context.Context) ([]*APIKey, error) {
+	var resp struct {
+		APIKeys []*APIKey `json:"api_keys"`
+	}
+
+	err := c.do(ctx, http.MethodGet, "/api-keys", nil, nil, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.APIKeys, nil
+}
+
+// POST /api-keys. Сам ключ (Plaintext) возвращается только в этом ответе.
+// expiresAt nil - бессрочный ключ
+func (c *Client) CreateAPIKey(ctx context.Context, name string, scopes []string, expiresAt *time.Time) (*APIKey, error) {
+	input := struct {
+		Name      string     `json:"name"`
+		Scopes    []string   `json:"scopes"`
+		ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	}{name, scopes, expiresAt}
+
+	return c.apiKeyRequest(ctx, http.MethodPost, "/api-keys", input)
+}
+
+// GET /api-keys/:id
+func (c *Client) GetAPIKey(ctx context.Context, id int64) (*APIKey, error) {
+	return c.apiKeyRequest(ctx, http.MethodGet, fmt.Sprintf("/api-keys/%d", id), nil)
+}
+
+// DELETE /api-keys/:id
+func (c *Client) RevokeAPIKey(ctx context.Context, id int64) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/api-keys/%d", id), nil, nil, nil)
+}
+
+// POST /api-keys/:id/rotate выпускает новый ключ, старый работает еще gracePeriod.
+// Отрицательный gracePeriod - значение сервера по умолчанию (24h)
+func (c *Client) RotateAPIKey(ctx context.Context, id int64, gracePeriod time.Duration) (*APIKey, error) {
+	var input struct {
+		GracePeriod string `json:"grace_period,omitempty"`
+	}
+	if gracePeriod >= 0 {
+		input.GracePeriod = gracePeriod.String()
+	}
+
+	return c.apiKeyRequest(ctx, http.MethodPost, fmt.Sprintf("/api-keys/%d/rotate", id), input)
+}
+
+func (c *Client) apiKeyRequest(ctx context.Context, method, path string, body any) (*APIKey, error) {
+	var resp struct {
+		APIKey *APIKey `json:"api_key"`
+	}
+
+	err := c.do(ctx, method, path, nil, body, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.APIKey, nil
+}